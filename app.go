@@ -4,22 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"image/png"
+	"imcrypt_v3/backend/autotype"
+	"imcrypt_v3/backend/breach"
+	"imcrypt_v3/backend/clipboard"
 	"imcrypt_v3/backend/crypto"
 	"imcrypt_v3/backend/database"
+	"imcrypt_v3/backend/fido2"
 	"imcrypt_v3/backend/file"
 	"imcrypt_v3/backend/fs"
 	"imcrypt_v3/backend/generate"
+	"imcrypt_v3/backend/history"
 	"imcrypt_v3/backend/key"
+	"imcrypt_v3/backend/power"
+	"imcrypt_v3/backend/sshkey"
 	"imcrypt_v3/backend/storage"
+	"imcrypt_v3/backend/twofactor"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -32,19 +44,80 @@ import (
 )
 
 type App struct {
-	ctx context.Context
-	fd  *file.File
-	aet *time.Timer // auth expiration timer
+	ctx    context.Context
+	active *vault   // the vault every API works on, see current
+	vaults []*vault // every loaded vault, in the order they were loaded
+
+	// Guards the vaults, which one is active and their session state (timers, ids, held back
+	// keys, ssh-agents and password resets). Wails calls APIs concurrently, and the lock timers
+	// and watchers run on their own goroutines.
+	mu sync.Mutex
+
+	clip      *time.Timer // clears the last copied field from the clipboard
+	clipClear func()
+}
+
+// A loaded image, along with its session. The file and history are set once, before the vault
+// is shared, everything else is guarded by App.mu.
+type vault struct {
+	fd   *file.File     // nil until an image is loaded
+	id   []byte         // the storage's id, once it's been unlocked
+	aet  *time.Timer    // auth expiration timer, reset by activity
+	hist *history.Stack // undo/redo history for the current session
+	ssha *sshkey.Agent  // ssh-agent serving keys for the current session, if started
+
+	idle          time.Duration // how long the session lasts without activity
+	minimizedLock time.Duration // how long the window can stay minimized before locking, 0 = never
+
+	pending       []byte // database key held back from the session until TFA is passed
+	resetRequired bool   // unlocked with the recovery phrase, so the master password must be changed
+
+	writing sync.Mutex // held while the image is written, so reads and other writes wait for it
+}
+
+type VaultInfo struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Unlocked bool   `json:"unlocked"`
+	Active   bool   `json:"active"`
 }
 
+var (
+	ErrPasswordResetRequired = errors.New("the master password must be changed before continuing")
+	ErrNoImage               = errors.New("no image has been loaded")
+)
+
+// How long the previously focused window gets to regain the focus before auto-typing into it
+const autoTypeFocusDelay = 500 * time.Millisecond
+
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{
+		active: newVault(),
+	}
+}
+
+func newVault() *vault {
+	return &vault{
+		hist: history.NewStack(),
+	}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	key.Delete() // Delete the key if it already exists, just in case
+	key.PurgeLegacy()
+
+	err := power.Watch(ctx, a.lockAll)
+	if err != nil {
+		fmt.Println("Unable to lock on suspend:", err)
+	}
+
+	go a.watchMinimized()
+
+	err = autotype.Listen(ctx, a.autoTypeActiveWindow)
+	if err != nil {
+		fmt.Println("Unable to listen for the auto-type hotkey:", err)
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -64,28 +137,96 @@ func (a *App) startup(ctx context.Context) {
 
 func (a *App) shutdown() {
 	fmt.Println("App has been shut down")
-	key.Delete()
+	key.DeleteAll()
+	a.clearClipboard()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	if a.fd != nil {
-		a.fd.Close()
+	for _, v := range a.vaults {
+		v.clearPending()
+		v.stopSSHAgent()
+		v.writing.Lock()
+		v.fd.Close()
+		v.writing.Unlock()
 	}
 }
 
-// API: Closes the current login session and releases the file
+// API: Closes the active vault's login session and releases its file, switching to the
+// most recently loaded vault that's still open, if any
 func (a *App) CloseSession() []any {
-	if a.fd != nil {
-		a.fd.Close()
-		a.fd = nil
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The closed vault is dropped, so calls still holding onto it only ever see a closed file
+	v := a.active
+
+	if v.fd != nil {
+		v.writing.Lock()
+		v.fd.Close()
+		v.writing.Unlock()
+	}
+
+	if v.aet != nil {
+		v.aet.Stop()
+		v.aet = nil
 	}
 
-	if a.aet != nil {
-		a.aet.Stop()
-		a.aet = nil
+	key.Delete(v.id)
+	key.Forget(v.id)
+	v.hist.Clear()
+	v.clearPending()
+	v.resetRequired = false
+	v.stopSSHAgent()
+
+	a.vaults = slices.DeleteFunc(a.vaults, func(loaded *vault) bool {
+		return loaded == v
+	})
+
+	if len(a.vaults) > 0 {
+		a.active = a.vaults[len(a.vaults)-1]
+	} else {
+		a.active = newVault()
 	}
 
 	return []any{}
 }
 
+// API: Lists the loaded vaults
+func (a *App) ListVaults() []any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	vaults := []VaultInfo{}
+
+	for _, v := range a.vaults {
+		vaults = append(vaults, VaultInfo{
+			Path:     v.fd.Path,
+			Name:     v.fd.GetName(),
+			Unlocked: key.Has(v.id),
+			Active:   v == a.active,
+		})
+	}
+
+	return []any{nil, vaults}
+}
+
+// API: Makes the loaded vault at the given path the active one, returning whether its
+// session is still unlocked
+func (a *App) SwitchVault(path string) []any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v := a.findVault(path)
+	if v == nil {
+		return []any{"vault is not loaded"}
+	}
+
+	a.active = v
+
+	return []any{nil, key.Has(v.id)}
+}
+
 // API: Opens a file select dialog box, prompting the user to select a file,
 // returning the file's absolute path
 func (a *App) OpenFileDialog(title string, pattern string) []any {
@@ -120,7 +261,22 @@ func (a *App) LoadImage(path string) []any {
 		return []any{"file is not a png or jpg image"}
 	}
 
-	a.fd = file
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Loading a vault that's already open switches to it, keeping its session
+	if v := a.findVault(file.Path); v != nil {
+		file.Close()
+		a.active = v
+
+		return []any{}
+	}
+
+	v := newVault()
+	v.fd = file
+
+	a.vaults = append(a.vaults, v)
+	a.active = v
 
 	return []any{}
 }
@@ -133,7 +289,12 @@ func (a *App) FocusWindow() {
 
 // API: Checks if the loaded image has an Imcrypt storage file
 func (a *App) HasStorage() []any {
-	hasStorage, err := a.fd.HasStorage()
+	v := a.current()
+	if v.fd == nil {
+		return []any{ErrNoImage.Error()}
+	}
+
+	hasStorage, err := v.fd.HasStorage()
 	if err != nil {
 		return []any{err.Error()}
 	}
@@ -141,8 +302,15 @@ func (a *App) HasStorage() []any {
 	return []any{nil, hasStorage}
 }
 
-// API: Initializes a new Storage struct onto the loaded image
-func (a *App) InitializeStorage(password string) []any {
+// API: Initializes a new Storage struct onto the loaded image. If a key file path is given,
+// the file will be required (alongside the password) to unlock the image. If asked for, a
+// recovery phrase that can also unlock the image is generated and returned.
+func (a *App) InitializeStorage(password, keyFilePath string, withRecoveryPhrase bool) []any {
+	v := a.current()
+	if v.fd == nil {
+		return []any{ErrNoImage.Error()}
+	}
+
 	if len(password) < 1 {
 		return []any{"password must be at least 1 character long"}
 	}
@@ -152,12 +320,7 @@ func (a *App) InitializeStorage(password string) []any {
 		return []any{err.Error()}
 	}
 
-	passwordHash, err := crypto.Hash([]byte(password), passwordSalt)
-	if err != nil {
-		return []any{err.Error()}
-	}
-
-	encryptionSalt, err := crypto.GenerateSalt(8)
+	databaseKey, err := storage.GenerateDatabaseKey()
 	if err != nil {
 		return []any{err.Error()}
 	}
@@ -165,74 +328,92 @@ func (a *App) InitializeStorage(password string) []any {
 	storageId, _ := gonanoid.New()
 
 	store := storage.Storage{
-		Id:             []byte(storageId),
-		EncryptionSalt: encryptionSalt,
-		PasswordSalt:   passwordSalt,
+		Id:           []byte(storageId),
+		PasswordSalt: passwordSalt,
 	}
 
 	db := database.NewDatabase()
 
 	defaultSettings := database.Settings{
-		SessionLength: 600_000, // 10 minutes
+		SessionLength:  600_000, // 10 minutes
+		TrashRetention: 30,      // days
+		ExpiryWarning:  30,      // days
+		ClipboardClear: 30,      // seconds
 	}
 
 	db.Settings = defaultSettings
 
-	err = key.Set(store.Id, passwordHash)
+	err = key.Set(store.Id, databaseKey)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	a.mu.Lock()
+	v.id = store.Id
+	a.mu.Unlock()
+
+	err = store.SetPassword(password, keyFilePath, nil)
 	if err != nil {
+		key.Delete(store.Id)
 		return []any{err.Error()}
 	}
 
 	err = store.SetDatabase(&db)
 	if err != nil {
-		key.Delete()
+		key.Delete(store.Id)
 		return []any{err.Error()}
 	}
 
-	err = a.fd.WriteImcryptStorage(&store)
+	phrase := ""
+	if withRecoveryPhrase {
+		phrase, err = store.CreateRecoveryPhrase()
+		if err != nil {
+			key.Delete(store.Id)
+			return []any{err.Error()}
+		}
+	}
+
+	err = v.write(&store)
 	if err != nil {
-		key.Delete()
+		key.Delete(store.Id)
 		return []any{err.Error()}
 	}
 
-	a.createAuthTimeout(defaultSettings.SessionLength)
+	a.applySettings(v, defaultSettings)
+	v.hist.Clear()
 
-	return []any{}
+	return []any{nil, phrase}
 }
 
 // API: Generates a TFA secret and QR code image and recovery code, storing the former and returning everything
 func (a *App) GenerateTwoFactorSecret() []any {
+	v := a.current()
+
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      "Imcrypt",
-		AccountName: a.fd.GetName(),
+		AccountName: v.fd.GetName(),
 	})
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	storage, _, err := a.pull()
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
 	sec := key.Secret()
-	storage.TwoFactorSecret = []byte(sec)
-
-	recoveryCode, _ := gonanoid.Generate("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789", 50)
 
-	recoveryCodeSalt, err := crypto.GenerateSalt(8)
+	err = storage.SetTwoFactorSecret(sec)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	recoveryCodeHash, err := crypto.Hash([]byte(recoveryCode), recoveryCodeSalt)
+	recoveryCodes, err := storage.GenerateRecoveryCodes()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	storage.TwoFactorRecoveryHash = recoveryCodeHash
-	storage.TwoFactorRecoverySalt = recoveryCodeSalt
-
 	qrImage, err := key.Image(500, 500)
 	if err != nil {
 		return []any{err.Error()}
@@ -247,27 +428,26 @@ func (a *App) GenerateTwoFactorSecret() []any {
 	// b64 encode for JSON/Wails transport
 	b64str := base64.StdEncoding.EncodeToString(buf.Bytes())
 
-	err = a.fd.WriteImcryptStorage(storage)
+	err = v.write(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	return []any{nil, sec, b64str, recoveryCode}
+	return []any{nil, sec, b64str, recoveryCodes}
 }
 
 // API: Deletes TFA from storage
 func (a *App) DeleteTwoFactorSecret() []any {
-	storage, _, err := a.pull()
+	v := a.current()
+
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	storage.TwoFactorConfirmed = nil
-	storage.TwoFactorRecoveryHash = nil
-	storage.TwoFactorRecoverySalt = nil
-	storage.TwoFactorSecret = nil
+	storage.ClearTwoFactor()
 
-	err = a.fd.WriteImcryptStorage(storage)
+	err = v.write(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
@@ -275,330 +455,1775 @@ func (a *App) DeleteTwoFactorSecret() []any {
 	return []any{nil}
 }
 
-// API: Checks if TFA is setup on storage
-func (a *App) HasTwoFactorAuthentication() []any {
-	storage, _, err := a.pull()
+// API: Checks if the loaded image needs a key file to be unlocked
+func (a *App) RequiresKeyFile() []any {
+	v := a.current()
+
+	storage, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	return []any{nil, bytes.Equal(storage.TwoFactorConfirmed, []byte{1})}
+	return []any{nil, storage.RequiresKeyFile}
 }
 
-// API: Validates the incoming code against the stored TFA secret from storage
-func (a *App) ValidateTwoFactorCode(code string, shouldConfirm bool) []any {
-	storage, _, err := a.pull()
+// API: Checks if the loaded image needs a security key alongside the master password
+func (a *App) RequiresSecurityKey() []any {
+	v := a.current()
+
+	storage, err := v.read()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, storage.RequiresSecurityKey()}
+}
+
+// API: Checks if TFA is setup on storage. This doesn't need the key, as it's asked before
+// the second factor releases it.
+func (a *App) HasTwoFactorAuthentication() []any {
+	v := a.current()
+
+	storage, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	good := totp.Validate(code, string(storage.TwoFactorSecret))
+	return []any{nil, bytes.Equal(storage.TwoFactorConfirmed, []byte{1})}
+}
+
+// API: Validates the incoming code against the stored TFA secret from storage. While unlocking,
+// a valid code releases the key held back by UnlockLoadedImage, returning the loaded database.
+func (a *App) ValidateTwoFactorCode(code string, shouldConfirm bool) []any {
+	v := a.current()
 
-	if shouldConfirm && good {
-		storage.TwoFactorConfirmed = []byte{1}
+	if pending := a.heldKey(v); pending != nil {
+		defer clear(pending)
 
-		err = a.fd.WriteImcryptStorage(storage)
+		storage, err := v.read()
 		if err != nil {
 			return []any{err.Error()}
 		}
-	}
 
-	return []any{nil, good}
-}
+		err = a.checkLockout(storage)
+		if err != nil {
+			return []any{err.Error()}
+		}
 
-// API: Validates the incoming recovery code against the stored recovery code hash/salt
-func (a *App) ValidateTwoFactorRecoveryCode(code string) []any {
-	storage, _, err := a.pull()
-	if err != nil {
-		return []any{err.Error()}
-	}
+		secret, err := storage.GetTwoFactorSecretWith(pending)
+		if err != nil {
+			return []any{err.Error()}
+		}
 
-	hashedInput, err := crypto.Hash([]byte(code), storage.TwoFactorRecoverySalt)
-	if err != nil {
-		return []any{err.Error()}
-	}
+		good, err := storage.ValidateTwoFactorCode(secret, code, time.Now())
+		if err != nil || !good {
+			a.failAttempt(v, storage)
 
-	return []any{nil, bytes.Equal(hashedInput, storage.TwoFactorRecoveryHash)}
-}
+			if err != nil {
+				return []any{err.Error()}
+			}
 
-// API: Reads into a []byte the contents of the loaded image
-func (a *App) ReadLoadedImage() []any {
-	data, err := a.fd.ReadAll()
-	if err != nil {
-		key.Delete()
-		return []any{err.Error()}
-	}
+			return []any{nil, false}
+		}
 
-	return []any{nil, data}
-}
+		database, err := a.release(v, storage, true)
+		if err != nil {
+			return []any{err.Error()}
+		}
 
-// API: Unlocks the loaded image, returning the loaded database
-func (a *App) UnlockLoadedImage(password string) []any {
-	storage, err := a.fd.ReadImcryptStorage()
-	if err != nil {
-		return []any{err.Error()}
+		return []any{nil, true, database.Redacted()}
 	}
 
-	passwordHash, err := crypto.Hash([]byte(password), storage.PasswordSalt)
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = key.Set(storage.Id, passwordHash)
+	secret, err := storage.GetTwoFactorSecret()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	database, err := storage.GetDatabase()
+	good, err := storage.ValidateTwoFactorCode(secret, code, time.Now())
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	a.createAuthTimeout(database.Settings.SessionLength)
-
-	// Checks if a previous session recorded TFA data but it was never confirmed by the user (i.e. didn't
-	// pass the test code). This would mostly happen if the user's session timed out before they finished setup.
-	if !bytes.Equal(storage.TwoFactorConfirmed, []byte{1}) && storage.TwoFactorRecoveryHash != nil {
-		storage.TwoFactorRecoveryHash = nil
-		storage.TwoFactorRecoverySalt = nil
-		storage.TwoFactorSecret = nil
+	if good {
+		if shouldConfirm {
+			storage.TwoFactorConfirmed = []byte{1}
+		}
 
-		err = a.fd.WriteImcryptStorage(storage)
+		err = v.write(storage)
 		if err != nil {
 			return []any{err.Error()}
 		}
 	}
 
-	return []any{nil, database}
+	return []any{nil, good}
 }
 
-// API: Retrieves the database
-func (a *App) GetDatabase() []any {
-	storage, database, err := a.pull()
+// API: Validates the incoming recovery code against the stored recovery codes, using it up
+// if it's valid. Returns whether it was valid and how many unused codes remain. While
+// unlocking, a valid code releases the key held back by UnlockLoadedImage.
+func (a *App) ValidateTwoFactorRecoveryCode(code string) []any {
+	v := a.current()
+
+	storage, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	return []any{nil, database, storage.TwoFactorSecret != nil}
-}
+	pending := a.heldKey(v)
+	defer clear(pending)
 
-// API: Checks if the loaded storage's id and the key's id match, validating they are
-// meant to be used together
-func (a *App) IsAuthenticated() []any {
-	if a.fd == nil {
-		return []any{nil, false}
+	if pending == nil {
+		storage, _, err = a.pull(v)
+		if err != nil {
+			return []any{err.Error()}
+		}
+	} else {
+		err = a.checkLockout(storage)
+		if err != nil {
+			return []any{err.Error()}
+		}
 	}
 
-	storage, err := a.fd.ReadImcryptStorage()
+	good, err := storage.UseRecoveryCode(code)
 	if err != nil {
-		return []any{err.Error(), false}
+		return []any{err.Error()}
 	}
 
-	keyData, err := key.Get()
-	if err != nil {
-		if err == key.ErrKeyNotFound || err == key.ErrKeyExpired {
-			return []any{nil, false}
+	if !good {
+		if pending != nil {
+			a.failAttempt(v, storage)
 		}
 
-		return []any{err.Error(), false}
+		return []any{nil, false, storage.RemainingRecoveryCodes()}
+	}
+
+	if pending != nil {
+		_, err = a.release(v, storage, true)
+	} else {
+		err = v.write(storage)
+	}
+	if err != nil {
+		return []any{err.Error()}
 	}
 
-	return []any{nil, bytes.Equal(storage.Id, keyData.Id)}
+	return []any{nil, true, storage.RemainingRecoveryCodes()}
 }
 
-// API: Inserts new Items into the Database, returning the newly inserted Items' ids and
-// the updated Database
-func (a *App) InsertItems(itemsToInsert []database.InsertItemsArg) []any {
-	storage, database, err := a.pull()
+// API: Replaces the TFA recovery codes with new ones, invalidating the old ones
+func (a *App) RegenerateTwoFactorRecoveryCodes() []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	ids, err := database.InsertItems(itemsToInsert)
+	if !bytes.Equal(storage.TwoFactorConfirmed, []byte{1}) {
+		return []any{"two-factor authentication isn't set up"}
+	}
+
+	recoveryCodes, err := storage.GenerateRecoveryCodes()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	err = v.write(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
-		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
-		}
-	}()
-
-	return []any{nil, []any{ids, database}}
+	return []any{nil, recoveryCodes}
 }
 
-// API: Inserts new Groups into the Database, returning the newly inserted Groups' ids
-// and the updated Database
+// API: Counts the TFA recovery codes that haven't been used yet
+func (a *App) GetTwoFactorRecoveryCodeCount() []any {
+	v := a.current()
 
-func (a *App) InsertGroups(groups []database.Group) []any {
-	storage, database, err := a.pull()
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	ids, err := database.InsertGroups(groups)
-	if err != nil {
-		return []any{err.Error()}
+	return []any{nil, storage.RemainingRecoveryCodes()}
+}
+
+// API: Reads into a []byte the contents of the loaded image
+func (a *App) ReadLoadedImage() []any {
+	v := a.current()
+	if v.fd == nil {
+		return []any{ErrNoImage.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	data, err := v.fd.ReadAll()
 	if err != nil {
+		a.mu.Lock()
+		key.Delete(v.id)
+		a.mu.Unlock()
+
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
-		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
-		}
-	}()
-
-	return []any{nil, []any{ids, database}}
+	return []any{nil, data}
 }
 
-// API: Updates Items in the Database, returning the updated Database
-func (a *App) UpdateItemsById(updates []database.ItemUpdate) []any {
-	storage, database, err := a.pull()
+// API: Unlocks the loaded image, returning the loaded database. When TFA is set up, the key
+// is held back from the keyring until ValidateTwoFactorCode (or a recovery code) releases it,
+// and no database is returned. The key file path is only needed if the image requires one,
+// and the security key is asked for if the image requires it.
+func (a *App) UnlockLoadedImage(password, keyFilePath string) []any {
+	v := a.current()
+
+	storage, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = database.UpdateItemsById(updates)
+	err = a.checkLockout(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	auth, err := a.securityKey(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
+	upgrading := len(storage.KeySlots) == 0
+
+	databaseKey, err := storage.UnlockWithPassword(password, keyFilePath, auth)
+	if err != nil {
+		a.failAttempt(v, storage)
+		return []any{err.Error()}
+	}
+
+	// Older storages are moved onto key slots as they're unlocked
+	if upgrading {
+		err = v.write(storage)
 		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
+			return []any{err.Error()}
 		}
-	}()
+	}
 
-	return []any{nil, database}
+	return a.unlock(v, storage, databaseKey)
 }
 
-// API: Updates Groups in the Database, returning the updated Database
-func (a *App) UpdateGroupsById(updates []database.GroupUpdate) []any {
-	storage, database, err := a.pull()
+// API: Unlocks the loaded image with a key slot other than the master password, i.e. a key
+// file's path (KEY_FILE) or a teammate's private key (PUBLIC_KEY). Like UnlockLoadedImage,
+// the key is held back until TFA passes, if it's set up.
+func (a *App) UnlockWithKeySlot(slotType, secret string) []any {
+	v := a.current()
+
+	if slotType == storage.RECOVERY_SLOT {
+		return a.UnlockWithRecoveryPhrase(secret)
+	}
+
+	storage, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = database.UpdateGroupsById(updates)
+	err = a.checkLockout(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	databaseKey, err := storage.UnlockWithSlot(slotType, secret)
 	if err != nil {
+		a.failAttempt(v, storage)
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
-		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
-		}
-	}()
-
-	return []any{nil, database}
+	return a.unlock(v, storage, databaseKey)
 }
 
-// API: Deletes Items from the Database, returning the updated Database
-func (a *App) DeleteItemsById(ids []string) []any {
-	storage, database, err := a.pull()
+// API: Unlocks the loaded image with its recovery phrase, for when the master password has
+// been forgotten. Nothing else can be done until ChangeMasterPassword sets a new password.
+func (a *App) UnlockWithRecoveryPhrase(phrase string) []any {
+	v := a.current()
+
+	store, err := v.read()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = database.DeleteItemsById(ids)
+	err = a.checkLockout(store)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	databaseKey, err := store.UnlockWithSlot(storage.RECOVERY_SLOT, phrase)
 	if err != nil {
+		a.failAttempt(v, store)
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
-		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
-		}
-	}()
+	result := a.unlock(v, store, databaseKey)
+	if result[0] == nil {
+		a.mu.Lock()
+		v.resetRequired = true
+		a.mu.Unlock()
+	}
 
-	return []any{nil, database}
+	return result
 }
 
-// API: Deletes Groups from the Database, returning the updated Database
-func (a *App) DeleteGroupsById(ids []string) []any {
-	storage, database, err := a.pull()
+// API: Checks if the master password has to be changed before the session can continue
+func (a *App) IsPasswordResetRequired() []any {
+	v := a.current()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return []any{nil, v.resetRequired}
+}
+
+// API: Generates a new recovery phrase for the loaded image, replacing the previous one
+func (a *App) CreateRecoveryPhrase() []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = database.DeleteGroupsById(ids)
+	phrase, err := storage.CreateRecoveryPhrase()
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	err = storage.SetDatabase(database)
+	err = v.write(storage)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	go func() {
-		err = a.fd.WriteImcryptStorage(storage)
-		if err != nil {
-			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
-		}
-	}()
-
-	return []any{nil, database}
+	return []any{nil, phrase}
 }
 
-// API: Generates a password string based on the provided ruleset and charset
-func (a *App) GeneratePassword(ruleset database.Ruleset, previousPasswords []string) []any {
-	s := time.Now()
-	generated, err := generate.Generate(ruleset, previousPasswords)
-	fmt.Println(time.Since(s))
+// API: Retrieves the database
+func (a *App) GetDatabase() []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
 	if err != nil {
 		return []any{err.Error()}
 	}
 
-	return []any{nil, generated}
+	return []any{nil, database.Redacted(), storage.HasTwoFactorSecret()}
 }
 
-// API: Validates a password against the provided rules
-func (a *App) ValidatePassword(p string, ruleset database.Ruleset, pp []string) []any {
-	_, database, err := a.pull()
-	if err != nil {
-		return []any{err.Error()}
+// API: Checks if the loaded storage has an unlocked session
+func (a *App) IsAuthenticated() []any {
+	v := a.current()
+
+	if v.fd == nil {
+		return []any{nil, false}
 	}
 
-	report, err := database.ValidatePassword(p, ruleset, pp)
+	storage, err := v.read()
 	if err != nil {
-		return []any{err.Error()}
+		return []any{err.Error(), false}
 	}
 
-	return []any{nil, report}
+	return []any{nil, key.Has(storage.Id)}
 }
 
-// API: 'Properly' opens a given url string in the user's default browser. Runtime's BrowserOpenURL is being
+// API: Inserts new Items into the Database, returning the newly inserted Items' ids and
+// the updated Database
+func (a *App) InsertItems(itemsToInsert []database.InsertItemsArg) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	ids, err := database.InsertItems(itemsToInsert)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	capacity, err := v.fd.Capacity()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.ValidateNoteBudget(capacity)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("insert items", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, []any{ids, database.Redacted()}}
+}
+
+// API: Inserts new Groups into the Database, returning the newly inserted Groups' ids
+// and the updated Database
+
+func (a *App) InsertGroups(groups []database.Group) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	ids, err := database.InsertGroups(groups)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("insert groups", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, []any{ids, database.Redacted()}}
+}
+
+// API: Updates Items in the Database, returning the updated Database
+func (a *App) UpdateItemsById(updates []database.ItemUpdate) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.UpdateItemsById(updates)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	capacity, err := v.fd.Capacity()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.ValidateNoteBudget(capacity)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("update items", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Updates Groups in the Database, returning the updated Database
+func (a *App) UpdateGroupsById(updates []database.GroupUpdate) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.UpdateGroupsById(updates)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("update groups", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Moves Items into the Database's trash, returning the updated Database
+func (a *App) DeleteItemsById(ids []string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.DeleteItemsById(ids)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("delete items", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Moves Groups into the Database's trash, returning the updated Database
+func (a *App) DeleteGroupsById(ids []string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.DeleteGroupsById(ids)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("delete groups", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Restores Items from the Database's trash, returning the updated Database
+func (a *App) RestoreItems(ids []string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.RestoreItems(ids)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("restore items", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Restores Groups from the Database's trash, returning the updated Database
+func (a *App) RestoreGroups(ids []string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.RestoreGroups(ids)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("restore groups", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Permanently deletes everything in the Database's trash, returning the updated Database
+func (a *App) EmptyTrash() []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	database.EmptyTrash()
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("empty trash", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Reverts the most recent change to the Database made during this session, returning
+// the updated Database and the name of the reverted change
+func (a *App) Undo() []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	name, err := v.hist.Undo(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// The change may have been to the settings
+	a.applySettings(v, database.Settings)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted(), name}
+}
+
+// API: Re-applies the most recently undone change to the Database, returning the updated
+// Database and the name of the re-applied change
+func (a *App) Redo() []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	name, err := v.hist.Redo(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// The change may have been to the settings
+	a.applySettings(v, database.Settings)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted(), name}
+}
+
+// API: Checks if there are changes that can be undone or redone
+func (a *App) GetHistoryStatus() []any {
+	v := a.current()
+
+	canUndo, canRedo := v.hist.Status()
+
+	return []any{nil, canUndo, canRedo}
+}
+
+// API: Gets everything across the Database that has expired or will expire soon
+func (a *App) GetExpiryAlerts() []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.GetExpiryAlerts()}
+}
+
+// API: Searches the Items in the Database, returning the matching Items' ids
+func (a *App) SearchItems(query string) []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.SearchItems(query)}
+}
+
+// API: Opens a save dialog box and exports the Database as unencrypted JSON to the
+// selected path, returning the path
+func (a *App) ExportDatabase() []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	path, err := fs.SaveFileDialog(a.ctx, "Export", "imcrypt_export.json", []runtime.FileFilter{
+		{
+			DisplayName: "JSON (*.json)",
+			Pattern:     "*.json",
+		},
+	})
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	data, err := database.Export()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, path}
+}
+
+// API: Opens a file select dialog box and imports the selected native JSON export into the
+// Database, returning the newly inserted Items' ids and the updated Database
+func (a *App) ImportDatabase() []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	path, err := fs.OpenFileDialog(a.ctx, "Import", []runtime.FileFilter{
+		{
+			DisplayName: "JSON (*.json)",
+			Pattern:     "*.json",
+		},
+	})
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	ids, err := database.Import(data)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	capacity, err := v.fd.Capacity()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.ValidateNoteBudget(capacity)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("import", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, []any{ids, database.Redacted()}}
+}
+
+// API: Reveals the hidden answer to one of an Item's security questions
+func (a *App) RevealSecurityAnswer(itemId, questionId string) []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	qa, err := database.GetSecurityQuestion(itemId, questionId)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, qa.Answer}
+}
+
+// API: Generates a random answer to one of an Item's security questions using the question's
+// ruleset, avoiding answers used by other Items. Returns the updated Database and the answer.
+func (a *App) GenerateSecurityAnswer(itemId, questionId string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	qa, err := database.GetSecurityQuestion(itemId, questionId)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.ValidateRuleset(qa.Ruleset)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	answer, err := generate.Generate(qa.Ruleset, database.SecurityAnswers(itemId))
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.SetSecurityAnswer(itemId, questionId, answer)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("generate security answer", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted(), answer}
+}
+
+// API: Finds security answers shared by more than one Item, returning the ids of the Items
+// sharing each one
+func (a *App) GetReusedSecurityAnswers() []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.FindReusedSecurityAnswers()}
+}
+
+// API: Finds every Item that signs in through the given identity provider's Item
+func (a *App) GetOAuthDependents(itemId string) []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.FindOAuthDependents(itemId)}
+}
+
+// API: Encrypts the file at the given path (e.g. one dropped onto the window or picked with
+// OpenFileDialog) and attaches it to an Item, returning the attachment's id and the updated
+// Database
+func (a *App) AddAttachment(itemId, path string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	id, err := database.AddAttachment(itemId, filepath.Base(path), len(data))
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetAttachment(id, data)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	size, err := storage.Size()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	capacity, err := v.fd.Capacity()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	if size > capacity {
+		return []any{fmt.Sprintf("the attachment is too large for the loaded image, which is %d bytes short", size-capacity)}
+	}
+
+	v.hist.Record("add attachment", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, []any{id, database.Redacted()}}
+}
+
+// API: Decrypts one of an Item's attachments, returning its filename and its base64 encoded contents
+func (a *App) GetAttachment(itemId, attachmentId string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	attachment, err := database.GetAttachment(itemId, attachmentId)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	data, err := storage.GetAttachment(attachment.Id)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, attachment.Name, base64.StdEncoding.EncodeToString(data)}
+}
+
+// API: Removes an attachment from an Item, returning the updated Database
+func (a *App) RemoveAttachment(itemId, attachmentId string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.RemoveAttachment(itemId, attachmentId)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("remove attachment", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Generates an SSH key pair with the given algorithm (ED25519, RSA or ECDSA), returning
+// the OpenSSH formatted private key, the public key and its fingerprint
+func (a *App) GenerateSSHKey(algorithm string, bits int, comment, passphrase string) []any {
+	privateKey, publicKey, err := sshkey.Generate(algorithm, bits, comment, passphrase)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	fingerprint, err := sshkey.Fingerprint(publicKey)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, privateKey, publicKey, fingerprint}
+}
+
+// API: Opens a save dialog box and exports an SSH key Item's private key in OpenSSH format to
+// the selected path, along with its public key (.pub) next to it. Returns the path.
+func (a *App) ExportSSHKey(itemId string) []any {
+	v := a.current()
+
+	_, db, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	item, exists := db.Items[itemId]
+	if !exists || item.Type != database.SSH_KEY_ITEM {
+		return []any{fmt.Sprintf("cannot find SSH key Item with id %s", itemId)}
+	}
+
+	path, err := fs.SaveFileDialog(a.ctx, "Export SSH key", "id_"+strings.ToLower(strings.ReplaceAll(item.Title, " ", "_")), nil)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = os.WriteFile(path, []byte(item.SSHKey.PrivateKey), 0600)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = os.WriteFile(path+".pub", []byte(item.SSHKey.PublicKey+"\n"), 0644)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, path}
+}
+
+// API: Starts a local ssh-agent serving every SSH key Item marked for the agent, until the
+// session ends. Returns the agent's socket path, to be used as SSH_AUTH_SOCK.
+func (a *App) StartSSHAgent() []any {
+	v := a.current()
+
+	_, db, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	keys := []sshkey.AgentKey{}
+
+	for _, item := range db.Items {
+		if item.Type == database.SSH_KEY_ITEM && item.SSHKey.Agent && !item.Archived {
+			keys = append(keys, sshkey.AgentKey{
+				PrivateKey: item.SSHKey.PrivateKey,
+				Passphrase: item.SSHKey.Passphrase,
+				Comment:    item.Title,
+			})
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	v.stopSSHAgent()
+
+	agent, err := sshkey.StartAgent(keys)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// The session may have been locked while the keys were read, and locking stops the agent
+	if !key.Has(v.id) {
+		agent.Stop()
+		return []any{key.ErrKeyExpired.Error()}
+	}
+
+	v.ssha = agent
+
+	return []any{nil, agent.Path}
+}
+
+// API: Stops the ssh-agent, if it's running
+func (a *App) StopSSHAgent() []any {
+	v := a.current()
+
+	a.mu.Lock()
+	v.stopSSHAgent()
+	a.mu.Unlock()
+
+	return []any{}
+}
+
+// API: Gets the current one-time code for a Login Item's two factor secret, along with the
+// seconds it remains valid and the code that follows it. HOTP counters are advanced and saved.
+func (a *App) GetItemTOTP(itemId string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	secret := database.Items[itemId].TwoFactorSecret
+
+	code, err := database.GenerateTOTP(itemId, time.Now())
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// Only HOTP secrets change, when their counter is advanced
+	if database.Items[itemId].TwoFactorSecret == secret {
+		return []any{nil, code}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, code}
+}
+
+// API: Copies one of an Item's fields (see Database.UseItemField) to the clipboard, which is
+// cleared after the configured delay unless something else has been copied since. Returns the
+// updated Database, with the Item marked as used, and whether the copy was marked as secret
+// for clipboard managers.
+func (a *App) CopyItemField(itemId, field string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	value, err := database.UseItemField(itemId, field, time.Now())
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// Still copied, but the UI should warn that it may be kept in the clipboard's history
+	err = a.copyToClipboard(value, time.Duration(database.Settings.ClipboardClear)*time.Second)
+	if err != nil && err != clipboard.ErrNotSecret {
+		return []any{err.Error()}
+	}
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted(), err == nil}
+}
+
+// API: Auto-types a Login Item's sequence into the window that was focused before Imcrypt,
+// minimizing Imcrypt to hand the focus back. Returns the updated Database, with the Item marked
+// as used.
+func (a *App) AutoTypeItem(itemId string) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	actions, err := database.AutoTypeItem(itemId, time.Now())
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	runtime.WindowMinimise(a.ctx)
+	time.Sleep(autoTypeFocusDelay)
+
+	err = autotype.Type(actions)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = a.saveAutoTyped(v, storage, database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Parses an otpauth:// URI, a bare secret, or a Google Authenticator otpauth-migration://
+// export into two factor entries, each with the URI to store as an item's two factor secret
+func (a *App) ParseTwoFactor(input string) []any {
+	entries, err := twofactor.ParseAll(input)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, entries}
+}
+
+// API: Reads the QR code in an image file and parses it like ParseTwoFactor
+func (a *App) ParseTwoFactorQR(path string) []any {
+	text, err := twofactor.ReadQR(path)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return a.ParseTwoFactor(text)
+}
+
+// API: Fills in the two factor secrets of matching Login Items from an otpauth:// URI or a
+// Google Authenticator export (or a QR code image file of either). Returns the ids of the
+// updated Items and the entries that couldn't be matched to one.
+func (a *App) MigrateTwoFactorSecrets(input string) []any {
+	v := a.current()
+
+	if _, err := os.Stat(input); err == nil {
+		text, err := twofactor.ReadQR(input)
+		if err != nil {
+			return []any{err.Error()}
+		}
+
+		input = text
+	}
+
+	entries, err := twofactor.ParseAll(input)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	updated, unmatched := database.MigrateTwoFactorSecrets(entries)
+	if len(updated) == 0 {
+		return []any{nil, database.Redacted(), updated, unmatched}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("migrate two factor secrets", before, database)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted(), updated, unmatched}
+}
+
+// API: Lists the loaded image's key slots, each of which can unlock it on its own, apart from
+// the security key, which is only required alongside the master password
+func (a *App) ListKeySlots() []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, storage.ListKeySlots()}
+}
+
+// API: Adds a key slot, returning its id. The secret is a recovery phrase (RECOVERY), a key
+// file's path (KEY_FILE) or a teammate's base64 encoded public key (PUBLIC_KEY).
+func (a *App) AddKeySlot(slotType, label, secret string) []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	id, err := storage.AddKeySlot(slotType, label, secret)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = v.write(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, id, storage.ListKeySlots()}
+}
+
+// API: Removes a key slot, so it can no longer unlock the loaded image
+func (a *App) RemoveKeySlot(slotId string) []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.RemoveKeySlot(slotId)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = v.write(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, storage.ListKeySlots()}
+}
+
+// API: Changes the master password, requiring the key file alongside it if a path is given.
+// The current password (and key file, if it's required) must be given too, unless the image
+// was unlocked with the recovery phrase and the password has to be reset. A reset stops
+// requiring the security key, since the recovery phrase is also how a lost one is replaced.
+// Only the password slot changes, the database isn't re-encrypted.
+func (a *App) ChangeMasterPassword(currentPassword, currentKeyFilePath, password, keyFilePath string) []any {
+	v := a.current()
+
+	// This is the one thing allowed while a reset is required, so it can't go through pull
+	store, err := v.read()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	a.mu.Lock()
+	resetRequired := v.resetRequired
+	a.mu.Unlock()
+
+	var auth storage.Authenticator
+
+	// Otherwise anyone at an unlocked session could take the vault over
+	if !resetRequired {
+		err = a.checkLockout(store)
+		if err != nil {
+			return []any{err.Error()}
+		}
+
+		auth, err = a.securityKey(store)
+		if err != nil {
+			return []any{err.Error()}
+		}
+
+		err = store.VerifyPassword(currentPassword, currentKeyFilePath, auth)
+		if err != nil {
+			a.failAttempt(v, store)
+			return []any{err.Error()}
+		}
+	} else {
+		store.ForgetSecurityKey()
+	}
+
+	err = store.SetPassword(password, keyFilePath, auth)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = v.write(store)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	a.mu.Lock()
+	v.resetRequired = false
+	a.mu.Unlock()
+
+	return []any{nil}
+}
+
+// API: Requires the plugged in security key (a FIDO2 authenticator with hmac-secret) alongside
+// the master password, which must be given (with the key file, if it's required). The key is
+// touched twice, once to make its credential and once to wrap its share of the database key.
+// Returns the security key slot's id and the key slots.
+func (a *App) AddSecurityKey(password, keyFilePath, label string) []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = a.checkLockout(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	if storage.RequiresSecurityKey() {
+		return []any{"a security key is already required, remove it first"}
+	}
+
+	// Checked up front, so a wrong password doesn't need the key touched first
+	err = storage.VerifyPassword(password, keyFilePath, nil)
+	if err != nil {
+		a.failAttempt(v, storage)
+		return []any{err.Error()}
+	}
+
+	device, err := fido2.Find()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	runtime.EventsEmit(a.ctx, "e_securitykey")
+
+	credentialId, err := device.MakeCredential(v.fd.GetName())
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	id, err := storage.AddSecurityKey(password, keyFilePath, &touchPrompt{a, device}, credentialId, label)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = v.write(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, id, storage.ListKeySlots()}
+}
+
+// API: Stops requiring the security key, which has to be touched along with the master password
+// (and key file, if it's required) being given. Returns the key slots.
+func (a *App) RemoveSecurityKey(password, keyFilePath string) []any {
+	v := a.current()
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = a.checkLockout(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	auth, err := a.securityKey(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.RemoveSecurityKey(password, keyFilePath, auth)
+	if err != nil {
+		a.failAttempt(v, storage)
+		return []any{err.Error()}
+	}
+
+	err = v.write(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, storage.ListKeySlots()}
+}
+
+// API: Generates a key pair for a teammate, returning the base64 encoded private and public
+// keys. The teammate keeps the private key, and the public key is added as a PUBLIC_KEY slot.
+func (a *App) GenerateSharingKeyPair() []any {
+	privateKey, publicKey, err := storage.GenerateSharingKeyPair()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, privateKey, publicKey}
+}
+
+// API: Gets how long (in milliseconds) until the loaded image can be unlocked again, and how
+// many failed attempts have been made in a row. This doesn't need the key.
+func (a *App) GetLockoutStatus() []any {
+	v := a.current()
+
+	storage, err := v.read()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, storage.LockoutRemaining(time.Now()).Milliseconds(), storage.FailedAttempts}
+}
+
+// API: Sets how many failed unlock attempts in a row wipe the key (ending any session and
+// requiring the password again), 0 to never wipe it
+func (a *App) SetLockoutPolicy(wipeAfter int) []any {
+	v := a.current()
+
+	if wipeAfter < 0 {
+		return []any{"the number of attempts must be >= 0"}
+	}
+
+	storage, _, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	storage.WipeAfter = wipeAfter
+
+	err = v.write(storage)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil}
+}
+
+// API: Updates the Database's settings, returning the updated Database
+func (a *App) UpdateSettings(update database.SettingsUpdate) []any {
+	v := a.current()
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	before, err := database.Clone()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = database.UpdateSettings(update)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = storage.SetDatabase(database)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	v.hist.Record("update settings", before, database)
+	a.applySettings(v, database.Settings)
+
+	a.writeStorage(v, storage)
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Remembers the session in the OS keyring (or forgets it), so it can be resumed after a
+// restart until the session length runs out
+func (a *App) RememberSession(enabled bool) []any {
+	v := a.current()
+
+	if !enabled {
+		storage, err := v.read()
+		if err != nil {
+			return []any{err.Error()}
+		}
+
+		err = key.Forget(storage.Id)
+		if err != nil {
+			return []any{err.Error()}
+		}
+
+		return []any{nil}
+	}
+
+	storage, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	err = key.Remember(storage.Id, time.Duration(database.Settings.SessionLength)*time.Millisecond)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil}
+}
+
+// API: Resumes a remembered session for the loaded image, returning the loaded database
+func (a *App) ResumeSession() []any {
+	v := a.current()
+
+	storage, err := v.read()
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	created, err := key.Recall(storage.Id)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	database, err := storage.GetDatabase()
+	if err != nil {
+		key.Delete(storage.Id)
+		return []any{err.Error()}
+	}
+
+	// The session length may have been shortened since the session was remembered
+	remaining := time.Duration(database.Settings.SessionLength)*time.Millisecond - time.Since(created)
+	if remaining <= 0 {
+		key.Delete(storage.Id)
+		key.Forget(storage.Id)
+		return []any{key.ErrKeyExpired.Error()}
+	}
+
+	a.mu.Lock()
+	v.id = storage.Id
+	a.mu.Unlock()
+
+	a.applySettings(v, database.Settings)
+	a.createAuthTimeout(v, int(remaining.Milliseconds()))
+	v.hist.Clear()
+
+	return []any{nil, database.Redacted()}
+}
+
+// API: Generates a password string based on the provided ruleset and charset
+func (a *App) GeneratePassword(ruleset database.Ruleset, previousPasswords []string) []any {
+	s := time.Now()
+	generated, err := generate.Generate(ruleset, previousPasswords)
+	fmt.Println(time.Since(s))
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, generated}
+}
+
+// API: Validates a password against the provided rules
+func (a *App) ValidatePassword(p string, ruleset database.Ruleset, pp []string) []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	report, err := database.ValidatePassword(p, ruleset, pp)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	// Passwords are validated as they're typed, so only a local corpus is checked, and a
+	// broken one is reported with e_breachcheck rather than failing the validation
+	if len(database.Settings.BreachCorpus) > 0 && len(p) > 0 {
+		source, err := breach.Open(database.Settings.BreachCorpus)
+
+		var counts []int
+		if err == nil {
+			counts, err = breach.Check(source, []string{p})
+		}
+		if err != nil {
+			runtime.EventsEmit(a.ctx, "e_breachcheck", err.Error())
+		} else {
+			report.Breached = counts[0] > 0
+			report.BreachCount = counts[0]
+		}
+	}
+
+	return []any{nil, report}
+}
+
+// API: Checks every Login Item's password against the local breach corpus, or the Have I Been
+// Pwned range API if there's none and it's allowed (only hash prefixes are sent). Returns the
+// breached Items with how many times their passwords were seen, most seen first.
+func (a *App) CheckBreaches() []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	var source breach.Source
+
+	switch {
+	case len(database.Settings.BreachCorpus) > 0:
+		source, err = breach.Open(database.Settings.BreachCorpus)
+		if err != nil {
+			return []any{err.Error()}
+		}
+	case database.Settings.BreachOnline:
+		source = breach.NewOnline()
+	default:
+		return []any{"set up a breach corpus (or allow online checks) first"}
+	}
+
+	breached, err := database.CheckBreaches(source)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, breached}
+}
+
+// API: Audits the vault's Login Items for weak, reused, expired and rule breaking passwords,
+// missing TFA and duplicate websites
+func (a *App) AuditVault() []any {
+	v := a.current()
+
+	_, database, err := a.pull(v)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.Audit(time.Now())}
+}
+
+// API: 'Properly' opens a given url string in the user's default browser. Runtime's BrowserOpenURL is being
 // a bitch. Note: Be sure to include protocol if you need to use this again.
 func (a *App) OpenURLInBrowser(url string) {
 	if !strings.HasPrefix(strings.ToLower(url), "https://") {
@@ -736,9 +2361,64 @@ func (a *App) GetFaviconURL(pageURL string) []any {
 	return []any{nil, faviconURL}
 }
 
+// Helper: Gets the active vault. APIs take it once, up front, and only work on it from then
+// on, so switching vaults mid-call can't mix up two vaults' state.
+func (a *App) current() *vault {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.active
+}
+
+// Helper: Reads the Storage off the vault's image, once any write in progress has finished.
+// Otherwise a read-modify-write could start from the Storage as it was before that write, and
+// undo it.
+func (v *vault) read() (*storage.Storage, error) {
+	if v.fd == nil {
+		return nil, ErrNoImage
+	}
+
+	v.writing.Lock()
+	defer v.writing.Unlock()
+
+	return v.fd.ReadImcryptStorage()
+}
+
+// Helper: Writes the Storage onto the vault's image, after any write in progress
+func (v *vault) write(store *storage.Storage) error {
+	if v.fd == nil {
+		return ErrNoImage
+	}
+
+	v.writing.Lock()
+	defer v.writing.Unlock()
+
+	return v.fd.WriteImcryptStorage(store)
+}
+
 // Helper: Gets the file descriptor, storage, and database off of the temp file
-func (a *App) pull() (*storage.Storage, *database.Database, error) {
-	storage, err := a.fd.ReadImcryptStorage()
+func (a *App) pull(v *vault) (*storage.Storage, *database.Database, error) {
+	storage, database, err := a.load(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a.touch(v)
+
+	return storage, database, nil
+}
+
+// Helper: Like pull, but without pushing the session timeout back
+func (a *App) load(v *vault) (*storage.Storage, *database.Database, error) {
+	a.mu.Lock()
+	resetRequired := v.resetRequired
+	a.mu.Unlock()
+
+	if resetRequired {
+		return nil, nil, ErrPasswordResetRequired
+	}
+
+	storage, err := v.read()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -751,15 +2431,441 @@ func (a *App) pull() (*storage.Storage, *database.Database, error) {
 	return storage, database, nil
 }
 
+// Helper: Writes the Storage onto the vault's image in the background, emitting e_storagewrite
+// if it fails
+func (a *App) writeStorage(v *vault, store *storage.Storage) {
+	v.writing.Lock()
+	go func() {
+		defer v.writing.Unlock()
+
+		err := v.fd.WriteImcryptStorage(store)
+		if err != nil {
+			runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
+		}
+	}()
+}
+
 // Helper: Creates an authentication timeout
-func (a *App) createAuthTimeout(timeInMilliseconds int) {
-	if a.aet != nil {
-		a.aet.Stop()
+func (a *App) createAuthTimeout(v *vault, timeInMilliseconds int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if v.aet != nil {
+		v.aet.Stop()
 	}
 
 	dur := time.Duration(timeInMilliseconds) * time.Millisecond
-	a.aet = time.AfterFunc(dur, func() {
-		key.Delete()
-		runtime.EventsEmit(a.ctx, "e_authexp")
+	v.aet = time.AfterFunc(dur, func() {
+		a.lock(v)
 	})
 }
+
+// Helper: Starts the session timeout and remembers the settings that depend on the session
+func (a *App) applySettings(v *vault, settings database.Settings) {
+	a.mu.Lock()
+	v.idle = time.Duration(settings.SessionLength) * time.Millisecond
+	v.minimizedLock = time.Duration(settings.MinimizedLock) * time.Millisecond
+	a.mu.Unlock()
+
+	a.createAuthTimeout(v, settings.SessionLength)
+}
+
+// Helper: Pushes the vault's session timeout back, since it's being used
+func (a *App) touch(v *vault) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A stopped timer has already locked the session
+	if v.aet != nil && v.aet.Stop() {
+		v.aet.Reset(v.idle)
+	}
+}
+
+// Helper: Locks the vault's session, letting the UI know if it was unlocked
+func (a *App) lock(v *vault) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if v.aet != nil {
+		v.aet.Stop()
+	}
+
+	unlocked := key.Has(v.id) || v.pending != nil
+
+	key.Delete(v.id)
+	v.clearPending()
+	v.stopSSHAgent()
+
+	if !unlocked {
+		return
+	}
+
+	if v == a.active {
+		runtime.EventsEmit(a.ctx, "e_authexp")
+	} else {
+		runtime.EventsEmit(a.ctx, "e_vaultexp", v.fd.Path)
+	}
+}
+
+// Helper: Locks every vault, e.g. when the system suspends or the screen locks
+func (a *App) lockAll() {
+	a.mu.Lock()
+	vaults := slices.Clone(a.vaults)
+	a.mu.Unlock()
+
+	for _, v := range vaults {
+		a.lock(v)
+	}
+}
+
+// Helper: Locks the vaults that don't allow the window to stay minimized for as long as it has
+func (a *App) watchMinimized() {
+	var since time.Time
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !runtime.WindowIsMinimised(a.ctx) {
+			since = time.Time{}
+			continue
+		}
+
+		if since.IsZero() {
+			since = time.Now()
+		}
+
+		expired := []*vault{}
+
+		a.mu.Lock()
+		for _, v := range a.vaults {
+			if v.minimizedLock > 0 && time.Since(since) >= v.minimizedLock {
+				expired = append(expired, v)
+			}
+		}
+		a.mu.Unlock()
+
+		for _, v := range expired {
+			a.lock(v)
+		}
+	}
+}
+
+// Helper: Stops the vault's ssh-agent, if it's running. Expects a.mu to be held.
+func (v *vault) stopSSHAgent() {
+	if v.ssha != nil {
+		v.ssha.Stop()
+		v.ssha = nil
+	}
+}
+
+// Helper: Copies text to the clipboard, marked as sensitive where the system supports it,
+// clearing it after the delay (if not 0) unless something else has been copied since. Returns
+// clipboard.ErrNotSecret if it was copied without being marked.
+func (a *App) copyToClipboard(text string, clearAfter time.Duration) error {
+	if a.clip != nil {
+		a.clip.Stop()
+		a.clip = nil
+	}
+
+	copied, err := clipboard.Write(text)
+	if err == clipboard.ErrUnsupported {
+		// Only the webview's clipboard is available, which has to be checked by its contents
+		// and can't mark the text as secret
+		err = runtime.ClipboardSetText(a.ctx, text)
+		if err == nil {
+			err = clipboard.ErrNotSecret
+		}
+	}
+	if err != nil && err != clipboard.ErrNotSecret {
+		return err
+	}
+
+	if clearAfter <= 0 {
+		return err
+	}
+
+	a.clipClear = func() {
+		if copied != nil {
+			copied.Clear()
+			return
+		}
+
+		current, err := runtime.ClipboardGetText(a.ctx)
+		if err == nil && current == text {
+			runtime.ClipboardSetText(a.ctx, "")
+		}
+	}
+	a.clip = time.AfterFunc(clearAfter, a.clipClear)
+
+	return err
+}
+
+// Helper: Clears a copied field from the clipboard right away, if it's still pending
+func (a *App) clearClipboard() {
+	if a.clip != nil && a.clip.Stop() {
+		a.clipClear()
+	}
+}
+
+// Helper: Auto-types into the focused window when the hotkey is pressed, if exactly one Login
+// Item matches its title. Otherwise Imcrypt is brought up with the e_autotype event (the title
+// and the matching ids, if unlocked) so the user can pick one for AutoTypeItem. Pressing the
+// hotkey doesn't count as using the vault, so it doesn't push the session timeout back.
+func (a *App) autoTypeActiveWindow() {
+	// This runs on the hotkey listener's goroutine, where a panic would take the app down
+	defer func() {
+		if r := recover(); r != nil {
+			runtime.EventsEmit(a.ctx, "e_autotypefail", fmt.Sprint(r))
+		}
+	}()
+
+	v := a.current()
+
+	title, err := autotype.ActiveWindowTitle()
+	if err != nil {
+		runtime.EventsEmit(a.ctx, "e_autotypefail", err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	unlocked := v.fd != nil && key.Has(v.id)
+	a.mu.Unlock()
+
+	var storage *storage.Storage
+	var database *database.Database
+	if unlocked {
+		storage, database, err = a.load(v)
+	}
+	if !unlocked || err != nil {
+		runtime.WindowUnminimise(a.ctx)
+		runtime.WindowShow(a.ctx)
+		runtime.EventsEmit(a.ctx, "e_autotype", title, []string{})
+		return
+	}
+
+	ids := database.MatchWindowTitle(title)
+	if len(ids) != 1 {
+		runtime.WindowUnminimise(a.ctx)
+		runtime.WindowShow(a.ctx)
+		runtime.EventsEmit(a.ctx, "e_autotype", title, ids)
+		return
+	}
+
+	actions, err := database.AutoTypeItem(ids[0], time.Now())
+	if err == nil {
+		err = autotype.Type(actions)
+	}
+	if err == nil {
+		err = a.saveAutoTyped(v, storage, database)
+	}
+	if err != nil {
+		runtime.EventsEmit(a.ctx, "e_autotypefail", err.Error())
+	}
+}
+
+// Helper: Saves an auto-typed Item as used, along with its HOTP counter if one was typed.
+// Only called once typing has succeeded, so a failed attempt doesn't burn a code.
+func (a *App) saveAutoTyped(v *vault, storage *storage.Storage, database *database.Database) error {
+	err := storage.SetDatabase(database)
+	if err != nil {
+		return err
+	}
+
+	a.writeStorage(v, storage)
+
+	return nil
+}
+
+// Helper: Finds the loaded vault for the image at the given path. Expects a.mu to be held.
+func (a *App) findVault(path string) *vault {
+	for _, v := range a.vaults {
+		if v.fd.Path == path {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// Helper: Holds the unlocked database key back until TFA passes, if it's set up, otherwise
+// releasing it right away. TOTP only gates this app, since its secret is encrypted with the
+// same key; the security key and key file are the factors that are actually part of the key.
+func (a *App) unlock(v *vault, storage *storage.Storage, databaseKey []byte) []any {
+	a.mu.Lock()
+	v.clearPending()
+	v.pending = databaseKey
+	v.resetRequired = false
+	a.mu.Unlock()
+
+	if bytes.Equal(storage.TwoFactorConfirmed, []byte{1}) {
+		return []any{nil, nil}
+	}
+
+	database, err := a.release(v, storage, false)
+	if err != nil {
+		return []any{err.Error()}
+	}
+
+	return []any{nil, database.Redacted()}
+}
+
+// Helper: Releases the key held back by UnlockLoadedImage into the keyring, starting the
+// session and tidying up the storage, which is written if it's dirty or gets tidied.
+// Returns the loaded database.
+func (a *App) release(v *vault, storage *storage.Storage, dirty bool) (*database.Database, error) {
+	err := a.releasePending(v, storage.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := storage.GetDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	a.applySettings(v, database.Settings)
+	v.hist.Clear()
+
+	purged := database.PurgeTrash()
+	if purged > 0 {
+		err = storage.SetDatabase(database)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Attachments removed during a previous session were kept around so the removal could
+	// be undone, but they're unreachable now
+	pruned := storage.PruneAttachments(database.AttachmentIds())
+
+	// Checks if a previous session recorded TFA data but it was never confirmed by the user (i.e. didn't
+	// pass the test code). This would mostly happen if the user's session timed out before they finished setup.
+	unconfirmed := !bytes.Equal(storage.TwoFactorConfirmed, []byte{1}) && storage.HasTwoFactorSecret()
+	if unconfirmed {
+		storage.ClearTwoFactor()
+	}
+
+	// Older storages kept the TFA secret in the clear
+	plain := storage.HasPlainTwoFactorSecret()
+	if plain {
+		secret, err := storage.GetTwoFactorSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		err = storage.SetTwoFactorSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reset := storage.ResetFailedAttempts()
+
+	if dirty || purged > 0 || pruned > 0 || unconfirmed || plain || reset {
+		err = v.write(storage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return database, nil
+}
+
+// Helper: Gets a copy of the key held back by UnlockLoadedImage, or nil if there's none. The
+// caller should clear it once it's done with it.
+func (a *App) heldKey(v *vault) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return bytes.Clone(v.pending)
+}
+
+// Helper: Moves the key held back by UnlockLoadedImage into the vault's session
+func (a *App) releasePending(v *vault, id []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if v.pending == nil {
+		return fmt.Errorf("the session was locked before it could start")
+	}
+
+	defer v.clearPending()
+
+	v.id = id
+
+	return key.Set(id, v.pending)
+}
+
+// Helper: Zeroes and forgets the key held back by UnlockLoadedImage. Expects a.mu to be held.
+func (v *vault) clearPending() {
+	for i := range v.pending {
+		v.pending[i] = 0
+	}
+
+	v.pending = nil
+}
+
+// Helper: Finds the security key if the storage requires one, otherwise returning nil
+func (a *App) securityKey(storage *storage.Storage) (storage.Authenticator, error) {
+	if !storage.RequiresSecurityKey() {
+		return nil, nil
+	}
+
+	device, err := fido2.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	return &touchPrompt{a, device}, nil
+}
+
+// A security key that asks the user to touch it (with the e_securitykey event) whenever it's
+// waiting on them
+type touchPrompt struct {
+	a      *App
+	device *fido2.Device
+}
+
+func (t *touchPrompt) HMACSecret(credentialId, salt []byte) ([]byte, error) {
+	runtime.EventsEmit(t.a.ctx, "e_securitykey")
+
+	return t.device.HMACSecret(credentialId, salt)
+}
+
+// Helper: Refuses unlock attempts while the storage is locked out, letting the UI know for
+// how long (in milliseconds) with the e_lockout event
+func (a *App) checkLockout(storage *storage.Storage) error {
+	remaining := storage.LockoutRemaining(time.Now())
+	if remaining == 0 {
+		return nil
+	}
+
+	runtime.EventsEmit(a.ctx, "e_lockout", remaining.Milliseconds())
+
+	return fmt.Errorf("too many failed attempts, try again in %s", remaining.Round(time.Second))
+}
+
+// Helper: Records a failed unlock attempt, wiping the key if the storage's policy says so
+func (a *App) failAttempt(v *vault, storage *storage.Storage) {
+	lockout := storage.RecordFailedAttempt(time.Now())
+
+	if storage.ShouldWipe() {
+		key.Delete(storage.Id)
+
+		a.mu.Lock()
+		v.clearPending()
+		a.mu.Unlock()
+
+		runtime.EventsEmit(a.ctx, "e_keywipe")
+	}
+
+	err := v.write(storage)
+	if err != nil {
+		runtime.EventsEmit(a.ctx, "e_storagewrite", err.Error())
+	}
+
+	if lockout > 0 {
+		runtime.EventsEmit(a.ctx, "e_lockout", lockout.Milliseconds())
+	}
+}
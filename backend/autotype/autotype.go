@@ -0,0 +1,70 @@
+package autotype
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrUnsupported = errors.New("auto-type isn't supported on this system")
+
+// The global hotkey that auto-types into the active window
+const Hotkey = "Ctrl+Alt+A"
+
+// The pause between keystrokes, unless a sequence sets its own with {DELAY=n}
+const defaultKeyDelay = 10 * time.Millisecond
+
+// A way of sending keystrokes to the focused window
+type keyboard interface {
+	typeRune(r rune) error
+	typeKey(k key) error
+	close() error
+}
+
+// Types the actions into the focused window
+func Type(actions []Action) error {
+	kb, err := open()
+	if err != nil {
+		return err
+	}
+	defer kb.close()
+
+	keyDelay := defaultKeyDelay
+
+	for _, action := range actions {
+		switch action.Kind {
+		case TEXT_TOKEN:
+			for _, r := range action.Text {
+				err = kb.typeRune(r)
+				if err != nil {
+					return err
+				}
+
+				time.Sleep(keyDelay)
+			}
+		case KEY_TOKEN:
+			err = kb.typeKey(keys[action.Key])
+			if err != nil {
+				return err
+			}
+
+			time.Sleep(keyDelay)
+		case DELAY_TOKEN:
+			time.Sleep(action.Delay)
+		case KEY_DELAY_TOKEN:
+			keyDelay = action.Delay
+		}
+	}
+
+	return nil
+}
+
+// Gets the title of the focused window
+func ActiveWindowTitle() (string, error) {
+	return activeWindowTitle()
+}
+
+// Calls onPress whenever the Hotkey is pressed, until the context is done
+func Listen(ctx context.Context, onPress func()) error {
+	return listen(ctx, onPress)
+}
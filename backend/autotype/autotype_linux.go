@@ -0,0 +1,38 @@
+package autotype
+
+import (
+	"context"
+	"os"
+)
+
+// X11 sessions type with XTest. Wayland doesn't let clients send input to each other, so
+// Wayland sessions (and consoles) type with a virtual uinput keyboard instead.
+func open() (keyboard, error) {
+	if isX11() {
+		return openX11()
+	}
+
+	return openUinput()
+}
+
+// Only X11 tells which window has the focus, which is why the hotkey needs it too
+func activeWindowTitle() (string, error) {
+	if !isX11() {
+		return "", ErrUnsupported
+	}
+
+	return x11ActiveWindowTitle()
+}
+
+// Global hotkeys need the desktop portal on Wayland, so they're only supported on X11
+func listen(ctx context.Context, onPress func()) error {
+	if !isX11() {
+		return ErrUnsupported
+	}
+
+	return x11Listen(ctx, onPress)
+}
+
+func isX11() bool {
+	return len(os.Getenv("DISPLAY")) > 0 && len(os.Getenv("WAYLAND_DISPLAY")) == 0
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package autotype
+
+import "context"
+
+func open() (keyboard, error) {
+	return nil, ErrUnsupported
+}
+
+func activeWindowTitle() (string, error) {
+	return "", ErrUnsupported
+}
+
+func listen(ctx context.Context, onPress func()) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,81 @@
+package autotype
+
+// A special key, as an X11 keysym and a Linux input event code
+type key struct {
+	keysym uint32
+	code   uint16
+}
+
+var keys = map[string]key{
+	"TAB":       {0xff09, 15},
+	"ENTER":     {0xff0d, 28},
+	"SPACE":     {0x0020, 57},
+	"BACKSPACE": {0xff08, 14},
+	"DELETE":    {0xffff, 111},
+	"ESC":       {0xff1b, 1},
+	"UP":        {0xff52, 103},
+	"DOWN":      {0xff54, 108},
+	"LEFT":      {0xff51, 105},
+	"RIGHT":     {0xff53, 106},
+	"HOME":      {0xff50, 102},
+	"END":       {0xff57, 107},
+	"PGUP":      {0xff55, 104},
+	"PGDN":      {0xff56, 109},
+	"INSERT":    {0xff63, 110},
+}
+
+// The names keys can go by in a sequence
+var keyAliases = map[string]string{
+	"TAB":       "TAB",
+	"ENTER":     "ENTER",
+	"SPACE":     "SPACE",
+	"BACKSPACE": "BACKSPACE",
+	"BKSP":      "BACKSPACE",
+	"BS":        "BACKSPACE",
+	"DELETE":    "DELETE",
+	"DEL":       "DELETE",
+	"ESC":       "ESC",
+	"UP":        "UP",
+	"DOWN":      "DOWN",
+	"LEFT":      "LEFT",
+	"RIGHT":     "RIGHT",
+	"HOME":      "HOME",
+	"END":       "END",
+	"PGUP":      "PGUP",
+	"PGDN":      "PGDN",
+	"INSERT":    "INSERT",
+	"INS":       "INSERT",
+}
+
+// The Linux input event code for the left shift key
+const leftShiftCode = 42
+
+// The Linux input event code for a character, and whether shift is needed to type it
+type layoutKey struct {
+	code  uint16
+	shift bool
+}
+
+// How each character is typed with a US keyboard layout, which is what a uinput keyboard
+// types with
+var usLayout = func() map[rune]layoutKey {
+	layout := map[rune]layoutKey{}
+
+	add := func(chars string, shifted string, codes ...uint16) {
+		for i, c := range []rune(chars) {
+			layout[c] = layoutKey{codes[i], false}
+		}
+
+		for i, c := range []rune(shifted) {
+			layout[c] = layoutKey{codes[i], true}
+		}
+	}
+
+	add("1234567890-=", "!@#$%^&*()_+", 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13)
+	add("qwertyuiop[]", "QWERTYUIOP{}", 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27)
+	add("asdfghjkl;'`", "ASDFGHJKL:\"~", 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41)
+	add("\\zxcvbnm,./", "|ZXCVBNM<>?", 43, 44, 45, 46, 47, 48, 49, 50, 51, 52, 53)
+	add(" \t\n", "", 57, 15, 28)
+
+	return layout
+}()
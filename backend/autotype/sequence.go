@@ -0,0 +1,205 @@
+package autotype
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TEXT_TOKEN      = "TEXT"      // literal text
+	FIELD_TOKEN     = "FIELD"     // a placeholder, e.g. {USERNAME} or {S:Custom field}
+	KEY_TOKEN       = "KEY"       // a special key, e.g. {TAB} or {TAB 3}
+	DELAY_TOKEN     = "DELAY"     // a pause, {DELAY 500}
+	KEY_DELAY_TOKEN = "KEY_DELAY" // the pause between keystrokes from then on, {DELAY=50}
+)
+
+// The sequence used for Items that don't set one
+const DefaultSequence = "{USERNAME}{TAB}{PASSWORD}{ENTER}"
+
+const (
+	maxRepeat   = 100
+	maxDelay    = 10 * time.Second
+	maxKeyDelay = time.Second
+)
+
+// The placeholders a sequence can contain, besides {S:<custom field name>}
+var fields = []string{"USERNAME", "PASSWORD", "EMAIL", "TITLE", "URL", "NOTES", "TOTP"}
+
+type Token struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"` // the text, field or key name
+	Count int    `json:"count"` // repetitions (KEY) or milliseconds (DELAY and KEY_DELAY)
+}
+
+// A resolved step of a sequence, ready to be typed
+type Action struct {
+	Kind  string        // TEXT, KEY, DELAY or KEY_DELAY
+	Text  string        // TEXT only
+	Key   string        // KEY only
+	Delay time.Duration // DELAY and KEY_DELAY only
+}
+
+// Parses an auto-type sequence. Text outside braces is typed as is, {{} and {}} type literal
+// braces, and everything else in braces is a placeholder, a key (optionally repeated, e.g.
+// {TAB 3}), a pause ({DELAY 500}), or the pause between keystrokes ({DELAY=50}).
+func Parse(sequence string) ([]Token, error) {
+	tokens := []Token{}
+	text := strings.Builder{}
+
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, Token{Kind: TEXT_TOKEN, Value: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(sequence); i++ {
+		c := sequence[i]
+
+		if c == '}' {
+			return nil, fmt.Errorf("unexpected } at position %d, use {}} to type one", i+1)
+		}
+
+		if c != '{' {
+			text.WriteByte(c)
+			continue
+		}
+
+		// {{} and {}} are escaped braces
+		if strings.HasPrefix(sequence[i:], "{{}") || strings.HasPrefix(sequence[i:], "{}}") {
+			text.WriteByte(sequence[i+1])
+			i += 2
+			continue
+		}
+
+		end := strings.IndexByte(sequence[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("the { at position %d is never closed", i+1)
+		}
+
+		token, err := parseBraces(sequence[i+1 : i+end])
+		if err != nil {
+			return nil, err
+		}
+
+		flush()
+		tokens = append(tokens, token)
+		i += end
+	}
+
+	flush()
+
+	return tokens, nil
+}
+
+// Parses what's between a pair of braces
+func parseBraces(inner string) (Token, error) {
+	if len(strings.TrimSpace(inner)) == 0 {
+		return Token{}, fmt.Errorf("{} is empty, use {{} or {}} to type a brace")
+	}
+
+	if name, ok := strings.CutPrefix(inner, "S:"); ok {
+		if len(name) == 0 {
+			return Token{}, fmt.Errorf("{S:} needs the name of a custom field")
+		}
+
+		return Token{Kind: FIELD_TOKEN, Value: "S:" + name}, nil
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(inner))
+
+	if ms, ok := strings.CutPrefix(upper, "DELAY="); ok {
+		count, err := parseCount(ms, int(maxKeyDelay/time.Millisecond))
+		if err != nil {
+			return Token{}, fmt.Errorf("{%s}: %v", inner, err)
+		}
+
+		return Token{Kind: KEY_DELAY_TOKEN, Count: count}, nil
+	}
+
+	name, arg, hasArg := strings.Cut(upper, " ")
+
+	if name == "DELAY" {
+		if !hasArg {
+			return Token{}, fmt.Errorf("{DELAY} needs a number of milliseconds, e.g. {DELAY 500}")
+		}
+
+		count, err := parseCount(arg, int(maxDelay/time.Millisecond))
+		if err != nil {
+			return Token{}, fmt.Errorf("{%s}: %v", inner, err)
+		}
+
+		return Token{Kind: DELAY_TOKEN, Count: count}, nil
+	}
+
+	for _, field := range fields {
+		if name == field {
+			if hasArg {
+				return Token{}, fmt.Errorf("{%s} can't be repeated", field)
+			}
+
+			return Token{Kind: FIELD_TOKEN, Value: field}, nil
+		}
+	}
+
+	key, ok := keyAliases[name]
+	if !ok {
+		return Token{}, fmt.Errorf("{%s} is not a known placeholder or key", inner)
+	}
+
+	count := 1
+	if hasArg {
+		var err error
+
+		count, err = parseCount(arg, maxRepeat)
+		if err != nil {
+			return Token{}, fmt.Errorf("{%s}: %v", inner, err)
+		}
+	}
+
+	return Token{Kind: KEY_TOKEN, Value: key, Count: count}, nil
+}
+
+func parseCount(s string, max int) (int, error) {
+	count, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+
+	if count < 0 || count > max {
+		return 0, fmt.Errorf("the number must be between 0 and %d", max)
+	}
+
+	return count, nil
+}
+
+// Turns the tokens into actions, looking up each placeholder's value
+func Resolve(tokens []Token, lookup func(field string) (string, error)) ([]Action, error) {
+	actions := []Action{}
+
+	for _, token := range tokens {
+		switch token.Kind {
+		case TEXT_TOKEN:
+			actions = append(actions, Action{Kind: TEXT_TOKEN, Text: token.Value})
+		case FIELD_TOKEN:
+			value, err := lookup(token.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(value) > 0 {
+				actions = append(actions, Action{Kind: TEXT_TOKEN, Text: value})
+			}
+		case KEY_TOKEN:
+			for range token.Count {
+				actions = append(actions, Action{Kind: KEY_TOKEN, Key: token.Value})
+			}
+		case DELAY_TOKEN, KEY_DELAY_TOKEN:
+			actions = append(actions, Action{Kind: token.Kind, Delay: time.Duration(token.Count) * time.Millisecond})
+		}
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,187 @@
+package autotype
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence string
+		want     []Token
+	}{
+		{
+			name:     "empty",
+			sequence: "",
+			want:     []Token{},
+		},
+		{
+			name:     "default sequence",
+			sequence: DefaultSequence,
+			want: []Token{
+				{Kind: FIELD_TOKEN, Value: "USERNAME"},
+				{Kind: KEY_TOKEN, Value: "TAB", Count: 1},
+				{Kind: FIELD_TOKEN, Value: "PASSWORD"},
+				{Kind: KEY_TOKEN, Value: "ENTER", Count: 1},
+			},
+		},
+		{
+			name:     "every field placeholder",
+			sequence: "{USERNAME}{PASSWORD}{EMAIL}{TITLE}{URL}{NOTES}{TOTP}",
+			want: []Token{
+				{Kind: FIELD_TOKEN, Value: "USERNAME"},
+				{Kind: FIELD_TOKEN, Value: "PASSWORD"},
+				{Kind: FIELD_TOKEN, Value: "EMAIL"},
+				{Kind: FIELD_TOKEN, Value: "TITLE"},
+				{Kind: FIELD_TOKEN, Value: "URL"},
+				{Kind: FIELD_TOKEN, Value: "NOTES"},
+				{Kind: FIELD_TOKEN, Value: "TOTP"},
+			},
+		},
+		{
+			name:     "placeholders are case insensitive",
+			sequence: "{username}{Tab}",
+			want: []Token{
+				{Kind: FIELD_TOKEN, Value: "USERNAME"},
+				{Kind: KEY_TOKEN, Value: "TAB", Count: 1},
+			},
+		},
+		{
+			name:     "text around placeholders",
+			sequence: "user: {USERNAME}!",
+			want: []Token{
+				{Kind: TEXT_TOKEN, Value: "user: "},
+				{Kind: FIELD_TOKEN, Value: "USERNAME"},
+				{Kind: TEXT_TOKEN, Value: "!"},
+			},
+		},
+		{
+			name:     "escaped braces",
+			sequence: "a{{}b{}}c",
+			want: []Token{
+				{Kind: TEXT_TOKEN, Value: "a{b}c"},
+			},
+		},
+		{
+			name:     "escaped braces around a placeholder",
+			sequence: "{{}{USERNAME}{}}",
+			want: []Token{
+				{Kind: TEXT_TOKEN, Value: "{"},
+				{Kind: FIELD_TOKEN, Value: "USERNAME"},
+				{Kind: TEXT_TOKEN, Value: "}"},
+			},
+		},
+		{
+			name:     "repeated keys",
+			sequence: "{TAB 3}{BS 0}{DEL 100}",
+			want: []Token{
+				{Kind: KEY_TOKEN, Value: "TAB", Count: 3},
+				{Kind: KEY_TOKEN, Value: "BACKSPACE", Count: 0},
+				{Kind: KEY_TOKEN, Value: "DELETE", Count: 100},
+			},
+		},
+		{
+			name:     "delays",
+			sequence: "{DELAY 500}{DELAY=50}{delay 10000}{DELAY=1000}",
+			want: []Token{
+				{Kind: DELAY_TOKEN, Count: 500},
+				{Kind: KEY_DELAY_TOKEN, Count: 50},
+				{Kind: DELAY_TOKEN, Count: 10000},
+				{Kind: KEY_DELAY_TOKEN, Count: 1000},
+			},
+		},
+		{
+			name:     "custom fields keep their case and spaces",
+			sequence: "{S:Pin code}{S:pin}",
+			want: []Token{
+				{Kind: FIELD_TOKEN, Value: "S:Pin code"},
+				{Kind: FIELD_TOKEN, Value: "S:pin"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.sequence)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", test.sequence, err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.sequence, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence string
+		contains string // part of the expected error
+	}{
+		{"unopened brace", "abc}", "unexpected }"},
+		{"unclosed brace", "{USERNAME", "never closed"},
+		{"empty braces", "{}", "is empty"},
+		{"blank braces", "{  }", "is empty"},
+		{"unknown placeholder", "{FOO}", "not a known placeholder or key"},
+		{"unknown key with a count", "{FOO 3}", "not a known placeholder or key"},
+		{"empty custom field", "{S:}", "needs the name of a custom field"},
+		{"repeated field", "{USERNAME 2}", "can't be repeated"},
+		{"delay without a number", "{DELAY}", "needs a number of milliseconds"},
+		{"delay that isn't a number", "{DELAY abc}", "is not a number"},
+		{"delay too long", "{DELAY 10001}", "between 0 and 10000"},
+		{"negative delay", "{DELAY -1}", "between 0 and 10000"},
+		{"key delay too long", "{DELAY=1001}", "between 0 and 1000"},
+		{"key delay that isn't a number", "{DELAY=}", "is not a number"},
+		{"repeat too high", "{TAB 101}", "between 0 and 100"},
+		{"repeat that isn't a number", "{TAB x}", "is not a number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := Parse(test.sequence)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %+v, want an error", test.sequence, tokens)
+			}
+
+			if !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", test.sequence, err, test.contains)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tokens, err := Parse("{USERNAME}{TAB 2}{S:Pin}{DELAY=20}{NOTES}{DELAY 100}x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{"USERNAME": "alice", "S:Pin": "1234"}
+
+	got, err := Resolve(tokens, func(field string) (string, error) {
+		return values[field], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty fields (NOTES) are skipped rather than typed as nothing
+	want := []Action{
+		{Kind: TEXT_TOKEN, Text: "alice"},
+		{Kind: KEY_TOKEN, Key: "TAB"},
+		{Kind: KEY_TOKEN, Key: "TAB"},
+		{Kind: TEXT_TOKEN, Text: "1234"},
+		{Kind: KEY_DELAY_TOKEN, Delay: 20 * time.Millisecond},
+		{Kind: DELAY_TOKEN, Delay: 100 * time.Millisecond},
+		{Kind: TEXT_TOKEN, Text: "x"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package autotype
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// From linux/uinput.h and linux/input-event-codes.h
+const (
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+
+	evSyn     = 0x00
+	evKey     = 0x01
+	synReport = 0
+	busUSB    = 0x03
+
+	// uinput_user_dev: the name, the input_id, ff_effects_max, then four arrays of 64 int32s
+	uinputNameSize = 80
+	uinputDevSize  = uinputNameSize + 8 + 4 + 4*64*4
+)
+
+// How long the desktop gets to pick up a new uinput device before it's typed with
+const uinputSettle = 300 * time.Millisecond
+
+// Types with a virtual keyboard created through /dev/uinput, which needs write access to it
+// (usually by being in the input group or a udev rule). It types with a US layout, so the
+// desktop's layout needs to match for anything but letters and digits.
+type uinputKeyboard struct {
+	file *os.File
+}
+
+func openUinput() (keyboard, error) {
+	file, err := os.OpenFile("/dev/uinput", os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a virtual keyboard, check /dev/uinput can be written to: %v", err)
+	}
+
+	kb := &uinputKeyboard{file: file}
+
+	err = kb.setup()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	time.Sleep(uinputSettle)
+
+	return kb, nil
+}
+
+func (kb *uinputKeyboard) setup() error {
+	fd := int(kb.file.Fd())
+
+	err := unix.IoctlSetInt(fd, uiSetEvBit, evKey)
+	if err != nil {
+		return err
+	}
+
+	codes := map[uint16]bool{leftShiftCode: true}
+	for _, k := range keys {
+		codes[k.code] = true
+	}
+	for _, k := range usLayout {
+		codes[k.code] = true
+	}
+
+	for code := range codes {
+		err = unix.IoctlSetInt(fd, uiSetKeyBit, int(code))
+		if err != nil {
+			return err
+		}
+	}
+
+	dev := make([]byte, uinputDevSize)
+	copy(dev[:uinputNameSize-1], "Imcrypt auto-type")
+	binary.NativeEndian.PutUint16(dev[uinputNameSize:], busUSB)
+	binary.NativeEndian.PutUint16(dev[uinputNameSize+2:], 0x1) // vendor
+	binary.NativeEndian.PutUint16(dev[uinputNameSize+4:], 0x1) // product
+	binary.NativeEndian.PutUint16(dev[uinputNameSize+6:], 0x1) // version
+
+	_, err = kb.file.Write(dev)
+	if err != nil {
+		return err
+	}
+
+	return unix.IoctlSetInt(fd, uiDevCreate, 0)
+}
+
+func (kb *uinputKeyboard) typeRune(r rune) error {
+	k, ok := usLayout[r]
+	if !ok {
+		return fmt.Errorf("the virtual keyboard can't type %q", r)
+	}
+
+	return kb.press(k.code, k.shift)
+}
+
+func (kb *uinputKeyboard) typeKey(k key) error {
+	return kb.press(k.code, false)
+}
+
+func (kb *uinputKeyboard) press(code uint16, shift bool) error {
+	events := [][2]int32{{int32(code), 1}, {int32(code), 0}}
+
+	if shift {
+		events = append([][2]int32{{leftShiftCode, 1}}, append(events, [2]int32{leftShiftCode, 0})...)
+	}
+
+	for _, e := range events {
+		err := kb.emit(evKey, uint16(e[0]), e[1])
+		if err != nil {
+			return err
+		}
+
+		err = kb.emit(evSyn, synReport, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Writes an input_event, leaving the time for the kernel to fill in
+func (kb *uinputKeyboard) emit(kind, code uint16, value int32) error {
+	timeSize := int(unsafe.Sizeof(unix.Timeval{}))
+	event := make([]byte, timeSize+8)
+
+	binary.NativeEndian.PutUint16(event[timeSize:], kind)
+	binary.NativeEndian.PutUint16(event[timeSize+2:], code)
+	binary.NativeEndian.PutUint32(event[timeSize+4:], uint32(value))
+
+	_, err := kb.file.Write(event)
+
+	return err
+}
+
+func (kb *uinputKeyboard) close() error {
+	unix.IoctlSetInt(int(kb.file.Fd()), uiDevDestroy, 0)
+
+	return kb.file.Close()
+}
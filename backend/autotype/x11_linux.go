@@ -0,0 +1,374 @@
+package autotype
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+const (
+	shiftKeysym = 0xffe1 // Shift_L
+	modifiers   = xproto.ModMaskShift | xproto.ModMaskControl | xproto.ModMask1 | xproto.ModMask4
+
+	// How long the hotkey's modifiers get to be released before typing anyway
+	releaseTimeout = 2 * time.Second
+)
+
+// Types by faking key events with the XTEST extension
+type x11Keyboard struct {
+	conn       *xgb.Conn
+	root       xproto.Window
+	min        xproto.Keycode
+	perKeycode int
+	keysyms    []xproto.Keysym
+	shift      xproto.Keycode
+
+	// A keycode without keysyms, temporarily mapped to characters the layout can't type
+	spare    xproto.Keycode
+	spareSym xproto.Keysym
+}
+
+func connectX11() (*xgb.Conn, xproto.Window, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to connect to the X server: %v", err)
+	}
+
+	return conn, xproto.Setup(conn).DefaultScreen(conn).Root, nil
+}
+
+func openX11() (keyboard, error) {
+	conn, root, err := connectX11()
+	if err != nil {
+		return nil, err
+	}
+
+	err = xtest.Init(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("the X server doesn't support XTEST: %v", err)
+	}
+
+	kb := &x11Keyboard{conn: conn, root: root}
+
+	err = kb.loadMapping()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	kb.shift, _, _ = kb.find(shiftKeysym)
+	kb.waitForRelease()
+
+	return kb, nil
+}
+
+func (kb *x11Keyboard) loadMapping() error {
+	setup := xproto.Setup(kb.conn)
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+
+	mapping, err := xproto.GetKeyboardMapping(kb.conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		return err
+	}
+
+	kb.min = setup.MinKeycode
+	kb.perKeycode = int(mapping.KeysymsPerKeycode)
+	kb.keysyms = mapping.Keysyms
+
+	// The highest keycodes are the likeliest to be unused
+	for i := len(kb.keysyms)/kb.perKeycode - 1; i >= 0 && kb.spare == 0; i-- {
+		unused := true
+
+		for _, keysym := range kb.keysyms[i*kb.perKeycode : (i+1)*kb.perKeycode] {
+			if keysym != 0 {
+				unused = false
+				break
+			}
+		}
+
+		if unused {
+			kb.spare = kb.min + xproto.Keycode(i)
+		}
+	}
+
+	return nil
+}
+
+// Finds the keycode that types the keysym, and whether it needs shift
+func (kb *x11Keyboard) find(keysym xproto.Keysym) (xproto.Keycode, bool, bool) {
+	for i := 0; i < len(kb.keysyms)/kb.perKeycode; i++ {
+		for column := 0; column < min(kb.perKeycode, 2); column++ {
+			if kb.keysyms[i*kb.perKeycode+column] == keysym {
+				return kb.min + xproto.Keycode(i), column == 1, true
+			}
+		}
+	}
+
+	return 0, false, false
+}
+
+// Waits for the hotkey's modifiers to be let go of, so they don't mix with what's typed
+func (kb *x11Keyboard) waitForRelease() {
+	deadline := time.Now().Add(releaseTimeout)
+
+	for time.Now().Before(deadline) {
+		pointer, err := xproto.QueryPointer(kb.conn, kb.root).Reply()
+		if err != nil || pointer.Mask&modifiers == 0 {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (kb *x11Keyboard) typeRune(r rune) error {
+	keysym := runeKeysym(r)
+
+	keycode, shift, ok := kb.find(keysym)
+	if ok {
+		return kb.press(keycode, shift)
+	}
+
+	if kb.spare == 0 {
+		return fmt.Errorf("the keyboard layout can't type %q", r)
+	}
+
+	if kb.spareSym != keysym {
+		err := kb.remapSpare(keysym)
+		if err != nil {
+			return err
+		}
+	}
+
+	return kb.press(kb.spare, false)
+}
+
+func (kb *x11Keyboard) typeKey(k key) error {
+	keycode, _, ok := kb.find(xproto.Keysym(k.keysym))
+	if !ok {
+		return fmt.Errorf("the keyboard layout doesn't have the key %#x", k.keysym)
+	}
+
+	return kb.press(keycode, false)
+}
+
+func (kb *x11Keyboard) press(keycode xproto.Keycode, shift bool) error {
+	if shift {
+		xtest.FakeInput(kb.conn, xproto.KeyPress, byte(kb.shift), 0, kb.root, 0, 0, 0)
+	}
+
+	xtest.FakeInput(kb.conn, xproto.KeyPress, byte(keycode), 0, kb.root, 0, 0, 0)
+	xtest.FakeInput(kb.conn, xproto.KeyRelease, byte(keycode), 0, kb.root, 0, 0, 0)
+
+	if shift {
+		xtest.FakeInput(kb.conn, xproto.KeyRelease, byte(kb.shift), 0, kb.root, 0, 0, 0)
+	}
+
+	// A round trip, so the events are sent before waiting between keystrokes
+	_, err := xproto.GetInputFocus(kb.conn).Reply()
+
+	return err
+}
+
+// Maps the keysym to the spare keycode, with and without shift
+func (kb *x11Keyboard) remapSpare(keysym xproto.Keysym) error {
+	keysyms := make([]xproto.Keysym, kb.perKeycode)
+	keysyms[0] = keysym
+
+	if kb.perKeycode > 1 {
+		keysyms[1] = keysym
+	}
+
+	err := xproto.ChangeKeyboardMappingChecked(kb.conn, 1, kb.spare, byte(kb.perKeycode), keysyms).Check()
+	if err != nil {
+		return err
+	}
+
+	kb.spareSym = keysym
+
+	return nil
+}
+
+func (kb *x11Keyboard) close() error {
+	if kb.spareSym != 0 {
+		kb.remapSpare(0)
+	}
+
+	kb.conn.Close()
+
+	return nil
+}
+
+// Latin-1 characters are their own keysyms, the rest of Unicode is offset by 0x1000000
+func runeKeysym(r rune) xproto.Keysym {
+	switch {
+	case r == '\n':
+		return xproto.Keysym(keys["ENTER"].keysym)
+	case r == '\t':
+		return xproto.Keysym(keys["TAB"].keysym)
+	case r >= 0x20 && r <= 0x7e, r >= 0xa0 && r <= 0xff:
+		return xproto.Keysym(r)
+	}
+
+	return xproto.Keysym(0x1000000 | r)
+}
+
+func x11ActiveWindowTitle() (string, error) {
+	conn, root, err := connectX11()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	active, err := x11Property(conn, root, "_NET_ACTIVE_WINDOW", xproto.AtomWindow)
+	if err != nil {
+		return "", err
+	}
+
+	if len(active) < 4 || xgb.Get32(active) == 0 {
+		return "", fmt.Errorf("no window has the focus")
+	}
+
+	window := xproto.Window(xgb.Get32(active))
+
+	utf8String, err := x11Atom(conn, "UTF8_STRING")
+	if err != nil {
+		return "", err
+	}
+
+	title, err := x11Property(conn, window, "_NET_WM_NAME", utf8String)
+	if err != nil || len(title) == 0 {
+		title, err = x11Property(conn, window, "WM_NAME", xproto.AtomString)
+		if err != nil {
+			return "", err
+		}
+
+		// WM_NAME is Latin-1
+		runes := make([]rune, len(title))
+		for i, b := range title {
+			runes[i] = rune(b)
+		}
+
+		return string(runes), nil
+	}
+
+	if !utf8.Valid(title) {
+		return "", fmt.Errorf("the window title isn't valid UTF-8")
+	}
+
+	return string(title), nil
+}
+
+func x11Atom(conn *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+
+	return reply.Atom, nil
+}
+
+func x11Property(conn *xgb.Conn, window xproto.Window, name string, kind xproto.Atom) ([]byte, error) {
+	atom, err := x11Atom(conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := xproto.GetProperty(conn, false, window, atom, kind, 0, 1024).Reply()
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.Value, nil
+}
+
+// Grabs the hotkey on the root window, whatever the state of caps and num lock
+func x11Listen(ctx context.Context, onPress func()) error {
+	conn, root, err := connectX11()
+	if err != nil {
+		return err
+	}
+
+	mask, keysym, err := parseHotkey(Hotkey)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	kb := &x11Keyboard{conn: conn, root: root}
+
+	err = kb.loadMapping()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	keycode, _, ok := kb.find(keysym)
+	if !ok {
+		conn.Close()
+		return fmt.Errorf("the keyboard layout doesn't have the hotkey's key")
+	}
+
+	for _, locks := range []uint16{0, xproto.ModMaskLock, xproto.ModMask2, xproto.ModMaskLock | xproto.ModMask2} {
+		err = xproto.GrabKeyChecked(conn, true, root, mask|locks, keycode, xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("unable to grab %s, another program may be using it: %v", Hotkey, err)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		for {
+			event, err := conn.WaitForEvent()
+			if event == nil && err == nil {
+				return // closed
+			}
+
+			if _, ok := event.(xproto.KeyPressEvent); ok {
+				go onPress()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Parses a hotkey such as Ctrl+Alt+A into its modifier mask and keysym
+func parseHotkey(hotkey string) (uint16, xproto.Keysym, error) {
+	parts := strings.Split(hotkey, "+")
+	mask := uint16(0)
+
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "ctrl", "control":
+			mask |= xproto.ModMaskControl
+		case "alt":
+			mask |= xproto.ModMask1
+		case "shift":
+			mask |= xproto.ModMaskShift
+		case "super":
+			mask |= xproto.ModMask4
+		default:
+			return 0, 0, fmt.Errorf("%s is not a modifier", part)
+		}
+	}
+
+	last := []rune(strings.ToLower(strings.TrimSpace(parts[len(parts)-1])))
+	if len(last) != 1 {
+		return 0, 0, fmt.Errorf("the hotkey must end with a single character")
+	}
+
+	return mask, runeKeysym(last[0]), nil
+}
@@ -0,0 +1,96 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The length of the hash prefix ranges are looked up by, as in the Have I Been Pwned API
+const prefixLength = 5
+
+// Somewhere the Have I Been Pwned SHA-1 hashes can be looked up, a range at a time
+type Source interface {
+	// Gets the hash suffixes (uppercase hex) starting with the prefix, with how many times
+	// each was seen in breaches
+	Range(prefix string) (map[string]int, error)
+}
+
+// Opens a local corpus, either the single SHA-1 file ordered by hash, or a directory of range
+// files named after their prefix (00000.txt through FFFFF.txt) like the downloader makes
+func Open(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the breach corpus: %v", err)
+	}
+
+	if info.IsDir() {
+		return rangeDir(path), nil
+	}
+
+	return openOrderedFile(path, info.Size())
+}
+
+// Counts how many times each password was seen in breaches, 0 meaning never. Passwords
+// sharing a hash prefix are looked up together, and only the prefix ever reaches the Source.
+func Check(source Source, passwords []string) ([]int, error) {
+	counts := make([]int, len(passwords))
+	ranges := map[string]map[string]int{}
+
+	for i, password := range passwords {
+		if len(password) == 0 {
+			continue
+		}
+
+		prefix, suffix := split(password)
+
+		r, ok := ranges[prefix]
+		if !ok {
+			var err error
+
+			r, err = source.Range(prefix)
+			if err != nil {
+				return nil, err
+			}
+
+			ranges[prefix] = r
+		}
+
+		counts[i] = r[suffix]
+	}
+
+	return counts, nil
+}
+
+// Splits the password's SHA-1 hash (uppercase hex) into its range prefix and suffix
+func split(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	return hash[:prefixLength], hash[prefixLength:]
+}
+
+// Parses a "HASH:COUNT" line. The count is optional, as some corpora only list hashes.
+func parseLine(line string) (string, int, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return "", 0, false
+	}
+
+	hash, countText, hasCount := strings.Cut(line, ":")
+	count := 1
+
+	if hasCount {
+		var err error
+
+		count, err = strconv.Atoi(countText)
+		if err != nil {
+			return "", 0, false
+		}
+	}
+
+	return strings.ToUpper(hash), count, true
+}
@@ -0,0 +1,156 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Lines in the ordered file are a 40 character hash, a colon and a count, so this always
+// holds a whole line
+const maxLineLength = 128
+
+// A directory of range files, each holding the suffixes for the prefix it's named after
+type rangeDir string
+
+func (dir rangeDir) Range(prefix string) (map[string]int, error) {
+	file, err := os.Open(filepath.Join(string(dir), prefix+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("the breach corpus is missing the range for %s: %v", prefix, err)
+	}
+	defer file.Close()
+
+	suffixes := map[string]int{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		suffix, count, ok := parseLine(scanner.Text())
+		if ok {
+			suffixes[suffix] = count
+		}
+	}
+
+	return suffixes, scanner.Err()
+}
+
+// The single file of every SHA-1 hash, ordered by hash, which is searched in place since it's
+// tens of gigabytes
+type orderedFile struct {
+	path string
+	size int64
+}
+
+func openOrderedFile(path string, size int64) (*orderedFile, error) {
+	f := &orderedFile{path: path, size: size}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Make sure it's a SHA-1 corpus rather than an NTLM one, which has shorter hashes
+	_, line, err := f.lineAt(file, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, _, ok := parseLine(line)
+	if !ok || len(hash) != 40 {
+		return nil, fmt.Errorf("the breach corpus doesn't look like a SHA-1 file ordered by hash")
+	}
+
+	return f, nil
+}
+
+func (f *orderedFile) Range(prefix string) (map[string]int, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Finds the first line at or after the smallest offset whose hash isn't below the prefix
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		_, line, err := f.lineAt(file, mid)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || strings.ToUpper(line[:min(len(line), prefixLength)]) >= prefix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	start, _, err := f.lineAt(file, lo)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Seek(start, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	suffixes := map[string]int{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		hash, count, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+
+		suffixes[hash[prefixLength:]] = count
+	}
+
+	return suffixes, scanner.Err()
+}
+
+// Reads the first line starting at or after the offset, returning where it starts. The line
+// is empty past the last one.
+func (f *orderedFile) lineAt(file *os.File, offset int64) (int64, string, error) {
+	if offset >= f.size {
+		return f.size, "", nil
+	}
+
+	// Starts a byte early, to tell whether the offset is already at a line's start
+	from := max(offset-1, 0)
+	buf := make([]byte, 2*maxLineLength)
+
+	n, err := file.ReadAt(buf, from)
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+	buf = buf[:n]
+
+	start := 0
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			return f.size, "", nil
+		}
+
+		start = newline + 1
+	}
+
+	rest := buf[start:]
+	if end := bytes.IndexByte(rest, '\n'); end >= 0 {
+		rest = rest[:end]
+	}
+
+	return from + int64(start), strings.TrimSpace(string(rest)), nil
+}
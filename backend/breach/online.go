@@ -0,0 +1,52 @@
+package breach
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const rangeAPI = "https://api.pwnedpasswords.com/range/"
+
+// The Have I Been Pwned range API. Only the first 5 characters of a hash are sent (the
+// k-anonymity model), and responses are padded so their size doesn't give the range away.
+type Online struct {
+	Client *http.Client
+}
+
+func NewOnline() *Online {
+	return &Online{Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (o *Online) Range(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, rangeAPI+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "Imcrypt")
+	req.Header.Set("Add-Padding", "true")
+
+	res, err := o.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach Have I Been Pwned: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Have I Been Pwned responded with %s", res.Status)
+	}
+
+	suffixes := map[string]int{}
+	scanner := bufio.NewScanner(res.Body)
+
+	for scanner.Scan() {
+		suffix, count, ok := parseLine(scanner.Text())
+		if ok && count > 0 { // padding entries have a count of 0
+			suffixes[suffix] = count
+		}
+	}
+
+	return suffixes, scanner.Err()
+}
@@ -0,0 +1,58 @@
+package clipboard
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrUnsupported = errors.New("the system clipboard can't be written to directly here")
+	ErrNotSecret   = errors.New("the copied text couldn't be marked as secret, so clipboard managers may keep it in their history")
+)
+
+// The hint clipboard managers honoring KDE's convention look for, so they don't keep a history
+// of the copied text
+const passwordManagerHint = "x-kde-passwordManagerHint"
+
+// Text copied to the system clipboard, marked as sensitive
+type Copy struct {
+	mu    sync.Mutex
+	owner owner
+}
+
+type owner interface {
+	owned() bool  // still holds the clipboard, i.e. nothing was copied since
+	clear() error // releases the clipboard, emptying it
+}
+
+// Copies text to the system clipboard, returning ErrUnsupported if it can only be copied
+// through the webview. If the text was copied but couldn't be marked as secret, the Copy is
+// returned along with ErrNotSecret.
+func Write(text string) (*Copy, error) {
+	o, err := write(text)
+	if o == nil {
+		return nil, err
+	}
+
+	return &Copy{owner: o}, err
+}
+
+// Checks that the clipboard still holds the copied text
+func (c *Copy) Owned() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.owner.owned()
+}
+
+// Empties the clipboard, unless something else has been copied since
+func (c *Copy) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.owner.owned() {
+		return nil
+	}
+
+	return c.owner.clear()
+}
@@ -0,0 +1,20 @@
+package clipboard
+
+import "os"
+
+// Prefers the Wayland clipboard when there's a Wayland session, falling back to X11 (which
+// XWayland also provides)
+func write(text string) (owner, error) {
+	if len(os.Getenv("WAYLAND_DISPLAY")) > 0 {
+		o, err := writeWayland(text)
+		if o != nil || len(os.Getenv("DISPLAY")) == 0 {
+			return o, err
+		}
+	}
+
+	if len(os.Getenv("DISPLAY")) > 0 {
+		return writeX11(text)
+	}
+
+	return nil, ErrUnsupported
+}
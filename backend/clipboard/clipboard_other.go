@@ -0,0 +1,7 @@
+//go:build !linux
+
+package clipboard
+
+func write(text string) (owner, error) {
+	return nil, ErrUnsupported
+}
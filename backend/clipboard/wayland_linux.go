@@ -0,0 +1,70 @@
+package clipboard
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// How long wl-copy gets to fail before it's assumed to be serving the clipboard
+const waylandStartup = 200 * time.Millisecond
+
+// wl-copy, kept in the foreground so it serves the clipboard for as long as it runs. It exits
+// on its own once something else is copied.
+type waylandOwner struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// Copies the text with wl-copy. The text goes through stdin so it never shows up in the
+// process list. wl-copy offers a single type, so the password manager hint can't be added and
+// ErrNotSecret is returned along with the owner.
+func writeWayland(text string) (owner, error) {
+	cmd := exec.Command("wl-copy", "--foreground", "--type", "text/plain;charset=utf-8")
+	cmd.Stdin = strings.NewReader(text)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	o := &waylandOwner{cmd: cmd, done: make(chan struct{})}
+
+	var waitErr error
+	go func() {
+		waitErr = cmd.Wait()
+		close(o.done)
+	}()
+
+	select {
+	case <-o.done:
+		if waitErr != nil {
+			return nil, fmt.Errorf("wl-copy failed: %s", strings.TrimSpace(stderr.String()))
+		}
+
+		return nil, fmt.Errorf("wl-copy exited before serving the clipboard")
+	case <-time.After(waylandStartup):
+	}
+
+	return o, ErrNotSecret
+}
+
+func (o *waylandOwner) owned() bool {
+	select {
+	case <-o.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (o *waylandOwner) clear() error {
+	o.cmd.Process.Kill()
+	<-o.done
+
+	return exec.Command("wl-copy", "--clear").Run()
+}
@@ -0,0 +1,205 @@
+package clipboard
+
+import (
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// The targets the copied text is offered as, besides TARGETS and the password manager hint
+var textTargets = []string{"UTF8_STRING", "text/plain;charset=utf-8", "text/plain", "STRING", "TEXT"}
+
+// An unmapped window owning the CLIPBOARD selection, serving the text to whoever asks for it
+// until another client takes the selection over
+type x11Owner struct {
+	conn      *xgb.Conn
+	window    xproto.Window
+	clipboard xproto.Atom
+	targets   xproto.Atom
+	hint      xproto.Atom
+	text      map[xproto.Atom]bool
+	data      []byte
+
+	mu   sync.Mutex
+	lost bool
+}
+
+func writeX11(text string) (owner, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	o, err := newX11Owner(conn, []byte(text))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go o.serve()
+
+	return o, nil
+}
+
+func newX11Owner(conn *xgb.Conn, data []byte) (*x11Owner, error) {
+	o := &x11Owner{conn: conn, data: data, text: map[xproto.Atom]bool{}}
+
+	var err error
+
+	if o.clipboard, err = o.atom("CLIPBOARD"); err != nil {
+		return nil, err
+	}
+
+	if o.targets, err = o.atom("TARGETS"); err != nil {
+		return nil, err
+	}
+
+	if o.hint, err = o.atom(passwordManagerHint); err != nil {
+		return nil, err
+	}
+
+	for _, name := range textTargets {
+		target, err := o.atom(name)
+		if err != nil {
+			return nil, err
+		}
+
+		o.text[target] = true
+	}
+
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+
+	o.window, err = xproto.NewWindowId(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = xproto.CreateWindowChecked(conn, 0, o.window, screen.Root, 0, 0, 1, 1, 0,
+		xproto.WindowClassInputOnly, screen.RootVisual, 0, nil).Check()
+	if err != nil {
+		return nil, err
+	}
+
+	err = xproto.SetSelectionOwnerChecked(conn, o.window, o.clipboard, xproto.TimeCurrentTime).Check()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := xproto.GetSelectionOwner(conn, o.clipboard).Reply()
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Owner != o.window {
+		return nil, ErrUnsupported
+	}
+
+	return o, nil
+}
+
+func (o *x11Owner) atom(name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(o.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+
+	return reply.Atom, nil
+}
+
+// Answers selection requests until the selection is lost or the connection is closed
+func (o *x11Owner) serve() {
+	for {
+		event, err := o.conn.WaitForEvent()
+		if event == nil && err == nil {
+			return // closed
+		}
+
+		switch e := event.(type) {
+		case xproto.SelectionRequestEvent:
+			o.respond(e)
+		case xproto.SelectionClearEvent:
+			o.release()
+			return
+		}
+	}
+}
+
+func (o *x11Owner) respond(e xproto.SelectionRequestEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.lost {
+		return
+	}
+
+	// Obsolete clients leave the property empty, expecting the target to be used instead
+	property := e.Property
+	if property == xproto.AtomNone {
+		property = e.Target
+	}
+
+	switch {
+	case e.Target == o.targets:
+		atoms := []xproto.Atom{o.targets, o.hint}
+		for target := range o.text {
+			atoms = append(atoms, target)
+		}
+
+		buf := make([]byte, len(atoms)*4)
+		for i, atom := range atoms {
+			xgb.Put32(buf[i*4:], uint32(atom))
+		}
+
+		xproto.ChangeProperty(o.conn, xproto.PropModeReplace, e.Requestor, property, xproto.AtomAtom, 32, uint32(len(atoms)), buf)
+	case e.Target == o.hint:
+		xproto.ChangeProperty(o.conn, xproto.PropModeReplace, e.Requestor, property, e.Target, 8, uint32(len("secret")), []byte("secret"))
+	case o.text[e.Target]:
+		xproto.ChangeProperty(o.conn, xproto.PropModeReplace, e.Requestor, property, e.Target, 8, uint32(len(o.data)), o.data)
+	default:
+		property = xproto.AtomNone
+	}
+
+	notify := xproto.SelectionNotifyEvent{
+		Time:      e.Time,
+		Requestor: e.Requestor,
+		Selection: e.Selection,
+		Target:    e.Target,
+		Property:  property,
+	}
+
+	xproto.SendEvent(o.conn, false, e.Requestor, xproto.EventMaskNoEvent, string(notify.Bytes()))
+}
+
+// Forgets the text and disconnects, once the selection is gone
+func (o *x11Owner) release() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.lost {
+		return
+	}
+
+	o.lost = true
+
+	for i := range o.data {
+		o.data[i] = 0
+	}
+
+	o.conn.Close()
+}
+
+func (o *x11Owner) owned() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return !o.lost
+}
+
+func (o *x11Owner) clear() error {
+	err := xproto.SetSelectionOwnerChecked(o.conn, xproto.AtomNone, o.clipboard, xproto.TimeCurrentTime).Check()
+
+	o.release()
+
+	return err
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"image"
 	"io"
+	"os"
 
 	"github.com/pquerna/otp/totp"
 	"golang.org/x/crypto/pbkdf2"
@@ -109,3 +110,19 @@ func Encrypt(payload, key []byte) ([]byte, error) {
 
 	return result, nil
 }
+
+// Hashes a file's contents, for use as a key file
+func HashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
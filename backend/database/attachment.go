@@ -0,0 +1,120 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// An attachment's details. Its (encrypted) contents are kept in the Storage, outside of
+// the Database, under the same id.
+type Attachment struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`  // the original filename
+	Size  int    `json:"size"`  // (bytes)
+	Added int64  `json:"added"` // (unix timestamp)
+}
+
+// Adds an attachment's details to an Item, returning the attachment's generated id
+func (db *Database) AddAttachment(itemId, name string, size int) (string, error) {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return "", fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return "", fmt.Errorf("the attachment name cannot be empty")
+	}
+
+	if size < 1 {
+		return "", fmt.Errorf("the attachment cannot be empty")
+	}
+
+	id, _ := gonanoid.New()
+	timestamp := time.Now().Unix()
+
+	item.Attachments = append(slices.Clone(item.Attachments), Attachment{
+		Id:    id,
+		Name:  name,
+		Size:  size,
+		Added: timestamp,
+	})
+	item.Updated = timestamp
+
+	db.Items[itemId] = item
+
+	return id, nil
+}
+
+// Finds an attachment's details on an Item
+func (db *Database) GetAttachment(itemId, attachmentId string) (Attachment, error) {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return Attachment{}, fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	for _, attachment := range item.Attachments {
+		if attachment.Id == attachmentId {
+			return attachment, nil
+		}
+	}
+
+	return Attachment{}, fmt.Errorf("cannot find attachment with id %s", attachmentId)
+}
+
+// Removes an attachment's details from an Item. Its contents are left in the Storage until
+// they're pruned, so the removal can still be undone during the session.
+func (db *Database) RemoveAttachment(itemId, attachmentId string) error {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	attachments := slices.DeleteFunc(slices.Clone(item.Attachments), func(attachment Attachment) bool {
+		return attachment.Id == attachmentId
+	})
+	if len(attachments) == len(item.Attachments) {
+		return fmt.Errorf("cannot find attachment with id %s", attachmentId)
+	}
+
+	if item.Identity.PhotoId == attachmentId {
+		item.Identity.PhotoId = ""
+	}
+
+	item.Attachments = attachments
+	item.Updated = time.Now().Unix()
+
+	db.Items[itemId] = item
+
+	return nil
+}
+
+// Gets the ids of every attachment referenced by an Item, including Items in the trash
+func (db *Database) AttachmentIds() []string {
+	ids := []string{}
+
+	for _, item := range db.Items {
+		for _, attachment := range item.Attachments {
+			ids = append(ids, attachment.Id)
+		}
+	}
+
+	for _, trashed := range db.Trash.Items {
+		for _, attachment := range trashed.Item.Attachments {
+			ids = append(ids, attachment.Id)
+		}
+	}
+
+	return ids
+}
+
+// Checks if the Item has an attachment with the given id
+func (item Item) HasAttachment(attachmentId string) bool {
+	return slices.ContainsFunc(item.Attachments, func(attachment Attachment) bool {
+		return attachment.Id == attachmentId
+	})
+}
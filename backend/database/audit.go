@@ -0,0 +1,284 @@
+package database
+
+import (
+	"cmp"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Passwords estimated to have less entropy than this (in bits) are weak
+const weakEntropy = 50
+
+// How much each finding takes off an Item's score (out of 100)
+const (
+	weakPenalty      = 40
+	reusedPenalty    = 30
+	expiredPenalty   = 15
+	violationPenalty = 10
+	twoFactorPenalty = 10
+)
+
+// Sites known to support one-time code two factor authentication. It's far from exhaustive,
+// so Items for other sites are never flagged.
+var twoFactorSites = []string{
+	"google.com", "youtube.com", "gmail.com", "microsoft.com", "live.com", "outlook.com",
+	"office.com", "apple.com", "icloud.com", "amazon.com", "aws.amazon.com", "github.com",
+	"gitlab.com", "bitbucket.org", "facebook.com", "instagram.com", "twitter.com", "x.com",
+	"linkedin.com", "reddit.com", "discord.com", "slack.com", "zoom.us", "dropbox.com",
+	"box.com", "paypal.com", "stripe.com", "coinbase.com", "kraken.com", "binance.com",
+	"steampowered.com", "epicgames.com", "ea.com", "ubisoft.com", "battle.net", "twitch.tv",
+	"npmjs.com", "pypi.org", "docker.com", "cloudflare.com", "digitalocean.com", "heroku.com",
+	"atlassian.com", "salesforce.com", "shopify.com", "wordpress.com", "tumblr.com",
+	"proton.me", "protonmail.com", "fastmail.com", "tutanota.com", "namecheap.com",
+	"godaddy.com", "ebay.com", "etsy.com", "wise.com", "revolut.com", "robinhood.com",
+	"okta.com", "mailchimp.com", "hubspot.com", "notion.so", "figma.com", "adobe.com",
+	"nintendo.com", "playstation.com", "xbox.com", "snapchat.com", "tiktok.com",
+}
+
+type AuditReport struct {
+	Score             int                `json:"score"`   // 0-100, the average of every audited Item's score
+	Audited           int                `json:"audited"` // (non-archived) Login Items with a password
+	Weak              []WeakPassword     `json:"weak"`
+	Reused            [][]string         `json:"reused"`  // Item ids sharing the same password
+	Expired           []string           `json:"expired"` // Item ids, per their Ruleset's TTL
+	RuleViolations    []RuleViolation    `json:"ruleViolations"`
+	MissingTwoFactor  []string           `json:"missingTwoFactor"` // Item ids of sites known to support TFA
+	DuplicateWebsites []DuplicateWebsite `json:"duplicateWebsites"`
+}
+
+type WeakPassword struct {
+	ItemId  string  `json:"itemId"`
+	Entropy float64 `json:"entropy"` // estimated, in bits
+}
+
+type RuleViolation struct {
+	ItemId string           `json:"itemId"`
+	Report ValidationReport `json:"report"`
+}
+
+type DuplicateWebsite struct {
+	Host    string   `json:"host"`
+	ItemIds []string `json:"itemIds"`
+}
+
+// Audits the (non-archived) Login Items for weak, reused, expired and rule breaking passwords,
+// sites without TFA, and websites used by more than one Item. Duplicate websites are only
+// reported, as there can be good reason for several accounts on a site, so they don't count
+// towards the score.
+func (db *Database) Audit(now time.Time) AuditReport {
+	report := AuditReport{
+		Score:             100,
+		Weak:              []WeakPassword{},
+		Reused:            [][]string{},
+		Expired:           []string{},
+		RuleViolations:    []RuleViolation{},
+		MissingTwoFactor:  []string{},
+		DuplicateWebsites: []DuplicateWebsite{},
+	}
+
+	ids := []string{}
+	for id, item := range db.Items {
+		if item.Type == LOGIN_ITEM && !item.Archived {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	penalties := map[string]int{}
+	byPassword := map[string][]string{}
+	byHost := map[string][]string{}
+
+	for _, id := range ids {
+		item := db.Items[id]
+
+		for _, host := range item.websiteHosts() {
+			if !slices.Contains(byHost[host], id) {
+				byHost[host] = append(byHost[host], id)
+			}
+		}
+
+		// Logins through an identity provider have neither a password nor TFA of their own
+		if len(item.Password) == 0 {
+			continue
+		}
+
+		report.Audited++
+		penalties[id] = 0
+
+		if len(item.TwoFactorSecret) == 0 && item.supportsTwoFactor() {
+			report.MissingTwoFactor = append(report.MissingTwoFactor, id)
+			penalties[id] += twoFactorPenalty
+		}
+
+		byPassword[item.Password] = append(byPassword[item.Password], id)
+
+		if entropy := estimateEntropy(item.Password); entropy < weakEntropy {
+			report.Weak = append(report.Weak, WeakPassword{ItemId: id, Entropy: math.Round(entropy*10) / 10})
+			penalties[id] += weakPenalty
+		}
+
+		// Items without a complete Ruleset (e.g. imported ones) have nothing to be held to
+		if db.ValidateRuleset(item.Ruleset) != nil {
+			continue
+		}
+
+		if !now.Before(item.PasswordExpiresAt()) {
+			report.Expired = append(report.Expired, id)
+			penalties[id] += expiredPenalty
+		}
+
+		var prevPasswords []string
+		if item.Ruleset.Reuse {
+			prevPasswords = item.PrevPasswords
+		}
+
+		validation, err := db.ValidatePassword(item.Password, item.Ruleset, prevPasswords)
+		if err == nil && !validation.IsValid {
+			report.RuleViolations = append(report.RuleViolations, RuleViolation{ItemId: id, Report: validation})
+			penalties[id] += violationPenalty
+		}
+	}
+
+	for _, sharing := range byPassword {
+		if len(sharing) < 2 {
+			continue
+		}
+
+		report.Reused = append(report.Reused, sharing)
+
+		for _, id := range sharing {
+			penalties[id] += reusedPenalty
+		}
+	}
+
+	slices.SortFunc(report.Reused, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	for host, sharing := range byHost {
+		if len(sharing) > 1 {
+			report.DuplicateWebsites = append(report.DuplicateWebsites, DuplicateWebsite{Host: host, ItemIds: sharing})
+		}
+	}
+
+	slices.SortFunc(report.DuplicateWebsites, func(a, b DuplicateWebsite) int {
+		return cmp.Compare(a.Host, b.Host)
+	})
+
+	if len(penalties) > 0 {
+		total := 0
+		for _, penalty := range penalties {
+			total += max(100-penalty, 0)
+		}
+
+		report.Score = int(math.Round(float64(total) / float64(len(penalties))))
+	}
+
+	return report
+}
+
+// Estimates a password's entropy from the size of the character classes it uses. Characters
+// repeating the previous one, or continuing a run like "abc" or "321", add almost nothing.
+func estimateEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+
+	runes := []rune(password)
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	length := 0.0
+	for i, r := range runes {
+		switch {
+		case i > 0 && r == runes[i-1]:
+			length += 0.1
+		case i > 1 && r-runes[i-1] == runes[i-1]-runes[i-2] && (r-runes[i-1] == 1 || r-runes[i-1] == -1):
+			length += 0.1
+		default:
+			length++
+		}
+	}
+
+	return length * math.Log2(float64(pool))
+}
+
+// Gets the hosts of the Item's websites, without any leading www.
+func (item Item) websiteHosts() []string {
+	hosts := []string{}
+
+	for _, website := range item.Websites {
+		if host := websiteHost(website); len(host) > 0 && !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+// Checks if any of the Item's websites is (on) a site known to support TFA
+func (item Item) supportsTwoFactor() bool {
+	for _, host := range item.websiteHosts() {
+		for _, site := range twoFactorSites {
+			if host == site || strings.HasSuffix(host, "."+site) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Gets a website's lowercase host, without any leading www. The scheme is optional.
+func websiteHost(website string) string {
+	website = strings.TrimSpace(website)
+	if len(website) == 0 {
+		return ""
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
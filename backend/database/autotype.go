@@ -0,0 +1,114 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"imcrypt_v3/backend/autotype"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The copyable fields (see UseItemField) auto-type placeholders stand for
+var autoTypeFields = map[string]string{
+	"USERNAME": "username",
+	"PASSWORD": "password",
+	"EMAIL":    "email",
+	"TITLE":    "title",
+	"NOTES":    "notes",
+	"TOTP":     "totp",
+}
+
+// Resolves a Login Item's auto-type sequence into what's to be typed, marking the Item as
+// used. HOTP counters are advanced if the sequence contains {TOTP}.
+func (db *Database) AutoTypeItem(itemId string, t time.Time) ([]autotype.Action, error) {
+	item, exists := db.Items[itemId]
+	if !exists || item.Type != LOGIN_ITEM {
+		return nil, fmt.Errorf("cannot find Login Item with id %s", itemId)
+	}
+
+	sequence := item.AutoType
+	if len(sequence) == 0 {
+		sequence = autotype.DefaultSequence
+	}
+
+	tokens, err := autotype.Parse(sequence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto-type sequence: %v", err)
+	}
+
+	actions, err := autotype.Resolve(tokens, func(placeholder string) (string, error) {
+		if name, ok := strings.CutPrefix(placeholder, "S:"); ok {
+			return db.itemField(itemId, item, "customFields."+name, t)
+		}
+
+		if placeholder == "URL" {
+			for _, website := range item.Websites {
+				if len(website) > 0 {
+					return website, nil
+				}
+			}
+
+			return "", nil
+		}
+
+		return db.itemField(itemId, item, autoTypeFields[placeholder], t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Generating a HOTP code updates the Item
+	item = db.Items[itemId]
+	item.LastUsed = t.Unix()
+	db.Items[itemId] = item
+
+	return actions, nil
+}
+
+// Finds the Login Items meant for a window, i.e. those whose title or one of whose websites'
+// host shows up in the window's title. The most recently used come first.
+func (db *Database) MatchWindowTitle(title string) []string {
+	title = strings.ToLower(title)
+	ids := []string{}
+
+	if len(strings.TrimSpace(title)) == 0 {
+		return ids
+	}
+
+	for id, item := range db.Items {
+		if item.Type != LOGIN_ITEM || item.Archived {
+			continue
+		}
+
+		if item.matchesWindow(title) {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.SortFunc(ids, func(a, b string) int {
+		if used := cmp.Compare(db.Items[b].LastUsed, db.Items[a].LastUsed); used != 0 {
+			return used
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	return ids
+}
+
+// Expects a lowercase window title
+func (item Item) matchesWindow(title string) bool {
+	itemTitle := strings.ToLower(strings.TrimSpace(item.Title))
+	if len(itemTitle) > 0 && strings.Contains(title, itemTitle) {
+		return true
+	}
+
+	for _, host := range item.websiteHosts() {
+		if strings.Contains(title, host) {
+			return true
+		}
+	}
+
+	return false
+}
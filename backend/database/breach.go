@@ -0,0 +1,54 @@
+package database
+
+import (
+	"cmp"
+	"imcrypt_v3/backend/breach"
+	"slices"
+)
+
+type BreachedItem struct {
+	ItemId string `json:"itemId"`
+	Title  string `json:"title"`
+	Count  int    `json:"count"` // how many times the password was seen in breaches
+}
+
+// Checks every Login Item's password against the breach source, returning the breached ones,
+// most prevalent first
+func (db *Database) CheckBreaches(source breach.Source) ([]BreachedItem, error) {
+	ids := []string{}
+	passwords := []string{}
+
+	for id, item := range db.Items {
+		if item.Type == LOGIN_ITEM && len(item.Password) > 0 {
+			ids = append(ids, id)
+			passwords = append(passwords, item.Password)
+		}
+	}
+
+	counts, err := breach.Check(source, passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	breached := []BreachedItem{}
+
+	for i, count := range counts {
+		if count > 0 {
+			breached = append(breached, BreachedItem{
+				ItemId: ids[i],
+				Title:  db.Items[ids[i]].Title,
+				Count:  count,
+			})
+		}
+	}
+
+	slices.SortFunc(breached, func(a, b BreachedItem) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.ItemId, b.ItemId)
+	})
+
+	return breached, nil
+}
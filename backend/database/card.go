@@ -0,0 +1,200 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	VISA_BRAND       = "VISA"
+	MASTERCARD_BRAND = "MASTERCARD"
+	AMEX_BRAND       = "AMEX"
+	DISCOVER_BRAND   = "DISCOVER"
+	DINERS_BRAND     = "DINERS"
+	JCB_BRAND        = "JCB"
+	UNIONPAY_BRAND   = "UNIONPAY"
+	MAESTRO_BRAND    = "MAESTRO"
+)
+
+type Card struct {
+	Cardholder     string `json:"cardholder"`
+	Number         string `json:"number"`      // digits only once normalized
+	ExpiryMonth    int    `json:"expiryMonth"` // 1-12
+	ExpiryYear     int    `json:"expiryYear"`  // 4 digits once normalized
+	CVV            string `json:"cvv"`
+	PIN            string `json:"pin"`
+	Brand          string `json:"brand"` // detected from the number if left empty
+	BillingAddress string `json:"billingAddress"`
+}
+
+// An issuer identification number range, inclusive, matched against the first
+// len(strconv.Itoa(from)) digits of a card number
+type iinRange struct {
+	from, to int
+	brand    string
+}
+
+// Ordered from most to least specific, since some ranges overlap (e.g. Discover's
+// 622126-622925 sits inside UnionPay's 62)
+var iinRanges = []iinRange{
+	{622126, 622925, DISCOVER_BRAND},
+	{2221, 2720, MASTERCARD_BRAND},
+	{3528, 3589, JCB_BRAND},
+	{6011, 6011, DISCOVER_BRAND},
+	{300, 305, DINERS_BRAND},
+	{644, 649, DISCOVER_BRAND},
+	{34, 34, AMEX_BRAND},
+	{37, 37, AMEX_BRAND},
+	{36, 36, DINERS_BRAND},
+	{38, 39, DINERS_BRAND},
+	{51, 55, MASTERCARD_BRAND},
+	{65, 65, DISCOVER_BRAND},
+	{62, 62, UNIONPAY_BRAND},
+	{50, 50, MAESTRO_BRAND},
+	{56, 69, MAESTRO_BRAND},
+	{4, 4, VISA_BRAND},
+}
+
+// Validates incoming Card
+func (db *Database) ValidateCard(card Card) error {
+	number := NormalizeCardNumber(card.Number)
+	if len(number) == 0 {
+		return fmt.Errorf("the card number cannot be empty")
+	}
+
+	if !isDigits(number) {
+		return fmt.Errorf("the card number can only contain digits, spaces and dashes")
+	}
+
+	if len(number) < 12 || len(number) > 19 {
+		return fmt.Errorf("the card number must be between 12 and 19 digits long")
+	}
+
+	if !PassesLuhn(number) {
+		return fmt.Errorf("the card number is invalid")
+	}
+
+	if card.ExpiryMonth != 0 || card.ExpiryYear != 0 {
+		if card.ExpiryMonth == 0 {
+			return fmt.Errorf("the expiry year needs a month")
+		}
+
+		if card.ExpiryYear == 0 {
+			return fmt.Errorf("the expiry month needs a year")
+		}
+
+		if card.ExpiryMonth < 1 || card.ExpiryMonth > 12 {
+			return fmt.Errorf("the expiry month must be between 1 and 12")
+		}
+
+		if card.ExpiryYear < 0 || (card.ExpiryYear > 99 && card.ExpiryYear < 2000) || card.ExpiryYear > 9999 {
+			return fmt.Errorf("the expiry year must be 2 or 4 digits")
+		}
+	}
+
+	cvv := strings.TrimSpace(card.CVV)
+	if len(cvv) > 0 && (!isDigits(cvv) || len(cvv) < 3 || len(cvv) > 4) {
+		return fmt.Errorf("the CVV must be 3 or 4 digits")
+	}
+
+	pin := strings.TrimSpace(card.PIN)
+	if len(pin) > 0 && (!isDigits(pin) || len(pin) < 4 || len(pin) > 12) {
+		return fmt.Errorf("the PIN must be between 4 and 12 digits")
+	}
+
+	return nil
+}
+
+// Normalizes a Card's data. The number is expected to have been validated already.
+func normalizeCard(card Card) Card {
+	card.Cardholder = strings.TrimSpace(card.Cardholder)
+	card.Number = NormalizeCardNumber(card.Number)
+	card.CVV = strings.TrimSpace(card.CVV)
+	card.PIN = strings.TrimSpace(card.PIN)
+	card.Brand = strings.ToUpper(strings.TrimSpace(card.Brand))
+	card.BillingAddress = strings.TrimSpace(card.BillingAddress)
+
+	if card.ExpiryYear > 0 && card.ExpiryYear < 100 {
+		card.ExpiryYear += 2000
+	}
+
+	if len(card.Brand) == 0 {
+		card.Brand = DetectCardBrand(card.Number)
+	}
+
+	return card
+}
+
+// Strips the spaces and dashes that card numbers are usually written with
+func NormalizeCardNumber(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number))
+}
+
+// Checks the number against the Luhn (mod 10) checksum
+func PassesLuhn(number string) bool {
+	sum := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// Detects a card's brand from its issuer identification number, returning an empty
+// string if it's unknown
+func DetectCardBrand(number string) string {
+	for _, r := range iinRanges {
+		digits := len(strconv.Itoa(r.from))
+		if len(number) < digits {
+			continue
+		}
+
+		prefix, err := strconv.Atoi(number[:digits])
+		if err != nil {
+			return ""
+		}
+
+		if prefix >= r.from && prefix <= r.to {
+			return r.brand
+		}
+	}
+
+	return ""
+}
+
+// Gets the time the card expires at. Cards are valid through the last day of their
+// expiry month. Returns the zero time if no expiry is set.
+func (card Card) ExpiresAt() time.Time {
+	if card.ExpiryMonth < 1 || card.ExpiryMonth > 12 || card.ExpiryYear < 1 {
+		return time.Time{}
+	}
+
+	return time.Date(card.ExpiryYear, time.Month(card.ExpiryMonth)+1, 1, 0, 0, 0, 0, time.Local)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
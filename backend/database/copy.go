@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Gets the value of one of an Item's fields to be copied, marking the Item as used. The field
+// is named after its JSON path, e.g. "password", "card.cvv", "customFields.<name>" or
+// "securityQuestions.<id>", and "totp" gives the current one-time code (advancing HOTP counters).
+func (db *Database) UseItemField(itemId, field string, t time.Time) (string, error) {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return "", fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	value, err := db.itemField(itemId, item, field, t)
+	if err != nil {
+		return "", err
+	}
+
+	if len(value) == 0 {
+		return "", fmt.Errorf("the %s is empty", field)
+	}
+
+	// Generating a HOTP code updates the Item
+	item = db.Items[itemId]
+	item.LastUsed = t.Unix()
+	db.Items[itemId] = item
+
+	return value, nil
+}
+
+func (db *Database) itemField(itemId string, item Item, field string, t time.Time) (string, error) {
+	path, key, _ := strings.Cut(field, ".")
+
+	switch strings.ToLower(path) {
+	case "customfields":
+		for _, f := range item.CustomFields {
+			if f.Name == key {
+				return f.Value, nil
+			}
+		}
+
+		return "", fmt.Errorf("the item doesn't have a custom field named %s", key)
+	case "securityquestions":
+		for _, qa := range item.SecurityQuestions {
+			if qa.Id == key {
+				return qa.Answer, nil
+			}
+		}
+
+		return "", fmt.Errorf("the item doesn't have a security question with id %s", key)
+	}
+
+	switch strings.ToLower(field) {
+	case "title":
+		return item.Title, nil
+	case "email":
+		return item.Email, nil
+	case "username":
+		return item.Username, nil
+	case "password":
+		return item.Password, nil
+	case "twofactorsecret":
+		return item.TwoFactorSecret, nil
+	case "totp":
+		code, err := db.GenerateTOTP(itemId, t)
+		if err != nil {
+			return "", err
+		}
+
+		return code.Code, nil
+	case "notes":
+		return item.Notes, nil
+	case "identity.number":
+		return item.Identity.Number, nil
+	case "card.cardholder":
+		return item.Card.Cardholder, nil
+	case "card.number":
+		return item.Card.Number, nil
+	case "card.cvv":
+		return item.Card.CVV, nil
+	case "card.pin":
+		return item.Card.PIN, nil
+	case "note.body":
+		return item.Note.Body, nil
+	case "sshkey.publickey":
+		return item.SSHKey.PublicKey, nil
+	case "sshkey.privatekey":
+		return item.SSHKey.PrivateKey, nil
+	case "sshkey.passphrase":
+		return item.SSHKey.Passphrase, nil
+	}
+
+	return "", fmt.Errorf("%s is not a field that can be copied", field)
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"fmt"
+	"imcrypt_v3/backend/autotype"
+	"imcrypt_v3/backend/twofactor"
 	"imcrypt_v3/backend/utils"
 	"slices"
 	"strings"
@@ -16,23 +18,34 @@ const (
 	ID_ITEM        = "ID"
 	BANK_CARD_ITEM = "CARD"
 	NOTE_ITEM      = "NOTE"
+	SSH_KEY_ITEM   = "SSH"
 )
 
 type Item struct {
-	Created         int64    `json:"created"`         // All items (unix timestamp)
-	Updated         int64    `json:"updated"`         // All items (unix timestamp)
-	Type            string   `json:"type"`            // All items
-	Title           string   `json:"title"`           // All items
-	Archived        bool     `json:"archived"`        // All items
-	Email           string   `json:"email"`           // Login item
-	Username        string   `json:"username"`        // Login item
-	Password        string   `json:"password"`        // Login item
-	PasswordCreated int64    `json:"passwordCreated"` // Login item
-	PrevPasswords   []string `json:"prevPasswords"`   // Login item
-	Websites        []string `json:"websites"`        // Login item
-	TwoFactorSecret string   `json:"twoFactorSecret"` // Login item
-	Notes           string   `json:"notes"`           // Login item
-	Ruleset         Ruleset  `json:"ruleset"`         // Login item
+	Created           int64        `json:"created"`           // All items (unix timestamp)
+	Updated           int64        `json:"updated"`           // All items (unix timestamp)
+	LastUsed          int64        `json:"lastUsed"`          // All items, when a field was last copied (unix timestamp)
+	Type              string       `json:"type"`              // All items
+	Title             string       `json:"title"`             // All items
+	Archived          bool         `json:"archived"`          // All items
+	CustomFields      []Field      `json:"customFields"`      // All items
+	Attachments       []Attachment `json:"attachments"`       // All items
+	Email             string       `json:"email"`             // Login item
+	Username          string       `json:"username"`          // Login item
+	Password          string       `json:"password"`          // Login item
+	PasswordCreated   int64        `json:"passwordCreated"`   // Login item
+	PrevPasswords     []string     `json:"prevPasswords"`     // Login item
+	Websites          []string     `json:"websites"`          // Login item
+	TwoFactorSecret   string       `json:"twoFactorSecret"`   // Login item
+	Notes             string       `json:"notes"`             // Login item
+	Ruleset           Ruleset      `json:"ruleset"`           // Login item
+	SecurityQuestions []QA         `json:"securityQuestions"` // Login item
+	OAuth             OAuth        `json:"oauth"`             // Login item
+	AutoType          string       `json:"autoType"`          // Login item, the auto-type sequence (autotype.DefaultSequence if empty)
+	Identity          Identity     `json:"identity"`          // ID item
+	Card              Card         `json:"card"`              // Bank card item
+	Note              Note         `json:"note"`              // Note item
+	SSHKey            SSHKey       `json:"sshKey"`            // SSH key item
 }
 
 type ItemUpdate struct {
@@ -41,6 +54,10 @@ type ItemUpdate struct {
 	GroupIds        []string `json:"groupIds"`
 	Mask            []string `json:"mask"`            // the fields on Item to actually update
 	RulesetMask     []string `json:"rulesetMask"`     // the fields on Item.Ruleset to actually update
+	IdentityMask    []string `json:"identityMask"`    // the fields on Item.Identity to actually update
+	CardMask        []string `json:"cardMask"`        // the fields on Item.Card to actually update
+	NoteMask        []string `json:"noteMask"`        // the fields on Item.Note to actually update
+	SSHKeyMask      []string `json:"sshKeyMask"`      // the fields on Item.SSHKey to actually update
 	IncludeGroupIds bool     `json:"includeGroupIds"` // include the GroupIds in the update
 }
 
@@ -77,7 +94,14 @@ type Ruleset struct {
 }
 
 type Settings struct {
-	SessionLength int `json:"sessionLength"`
+	SessionLength  int `json:"sessionLength"`  // milliseconds without activity before the session locks
+	TrashRetention int `json:"trashRetention"` // days before trashed entities are purged, 0 = never
+	ExpiryWarning  int `json:"expiryWarning"`  // days before something expires to start alerting
+	MinimizedLock  int `json:"minimizedLock"`  // milliseconds minimized before the session locks, 0 = never
+	ClipboardClear int `json:"clipboardClear"` // seconds before copied fields are cleared from the clipboard, 0 = never
+
+	BreachCorpus string `json:"breachCorpus"` // local Have I Been Pwned SHA-1 corpus, a file ordered by hash or a directory of ranges
+	BreachOnline bool   `json:"breachOnline"` // check breaches with the k-anonymity API when there's no local corpus
 }
 
 type SettingsUpdate struct {
@@ -88,6 +112,7 @@ type SettingsUpdate struct {
 type Database struct {
 	Items    map[string]Item  `json:"items"`
 	Groups   map[string]Group `json:"groups"`
+	Trash    Trash            `json:"trash"`
 	Settings Settings         `json:"settings"`
 }
 
@@ -96,15 +121,63 @@ func NewDatabase() Database {
 	return Database{
 		Items:  make(map[string]Item),
 		Groups: make(map[string]Group),
+		Trash: Trash{
+			Items:  make(map[string]TrashedItem),
+			Groups: make(map[string]TrashedGroup),
+		},
 	}
 }
 
+// Creates a deep copy of the Database
+func (db *Database) Clone() (*Database, error) {
+	gobbed, err := utils.Gobify(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var clone Database
+
+	err = utils.Degob(gobbed, &clone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clone, nil
+}
+
 // Updates the settings
 func (db *Database) UpdateSettings(update SettingsUpdate) error {
 	for _, field := range update.Mask {
 		switch strings.ToLower(field) {
 		case "sessionlength":
-			db.Settings.SessionLength = update.Settings.SessionLength * int(time.Millisecond)
+			if update.Settings.SessionLength < int(time.Minute/time.Millisecond) {
+				return fmt.Errorf("the session length must be at least a minute")
+			}
+			db.Settings.SessionLength = update.Settings.SessionLength
+		case "trashretention":
+			if update.Settings.TrashRetention < 0 {
+				return fmt.Errorf("the trash retention must be >= 0")
+			}
+			db.Settings.TrashRetention = update.Settings.TrashRetention
+		case "expirywarning":
+			if update.Settings.ExpiryWarning < 0 {
+				return fmt.Errorf("the expiry warning must be >= 0")
+			}
+			db.Settings.ExpiryWarning = update.Settings.ExpiryWarning
+		case "minimizedlock":
+			if update.Settings.MinimizedLock < 0 {
+				return fmt.Errorf("the minimized lock must be >= 0")
+			}
+			db.Settings.MinimizedLock = update.Settings.MinimizedLock
+		case "clipboardclear":
+			if update.Settings.ClipboardClear < 0 {
+				return fmt.Errorf("the clipboard clear delay must be >= 0")
+			}
+			db.Settings.ClipboardClear = update.Settings.ClipboardClear
+		case "breachcorpus":
+			db.Settings.BreachCorpus = strings.TrimSpace(update.Settings.BreachCorpus)
+		case "breachonline":
+			db.Settings.BreachOnline = update.Settings.BreachOnline
 		}
 	}
 
@@ -121,12 +194,27 @@ func (db *Database) InsertItems(itemsToInsert []InsertItemsArg) ([]string, error
 	ids := []string{}
 
 	for _, itemToInsert := range itemsToInsert {
+		// Attachments can only be added once the item exists
+		itemToInsert.Item.Attachments = nil
+
 		// Validate the incoming item
 		err := db.ValidateItem(itemToInsert.Item, itemToInsert.GroupIds, true, false)
 		if err != nil {
 			return ids, err
 		}
 
+		if strings.ToUpper(strings.TrimSpace(itemToInsert.Item.Type)) == LOGIN_ITEM {
+			err = db.ValidateTwoFactorSecret(itemToInsert.Item.TwoFactorSecret)
+			if err != nil {
+				return ids, err
+			}
+
+			err = db.ValidateAutoType(itemToInsert.Item.AutoType)
+			if err != nil {
+				return ids, err
+			}
+		}
+
 		// Create and append
 		id := db.GenerateId()
 
@@ -163,17 +251,21 @@ func (db *Database) InsertGroups(groups []Group) ([]string, error) {
 // Updates an Item
 func (db *Database) UpdateItemsById(updates []ItemUpdate) error {
 	for _, update := range updates {
-		item, exists := db.Items[update.ItemId]
+		existing, exists := db.Items[update.ItemId]
 		if !exists {
 			return fmt.Errorf("cannot find Item with id %s", update.ItemId)
 		}
 
+		item := existing
+
 		for _, field := range update.Mask {
 			switch strings.ToLower(field) {
 			case "title":
 				item.Title = update.Item.Title
 			case "archived":
 				item.Archived = update.Item.Archived
+			case "customfields":
+				item.CustomFields = update.Item.CustomFields
 			case "email":
 				item.Email = update.Item.Email
 			case "username":
@@ -187,6 +279,12 @@ func (db *Database) UpdateItemsById(updates []ItemUpdate) error {
 				item.TwoFactorSecret = update.Item.TwoFactorSecret
 			case "notes":
 				item.Notes = update.Item.Notes
+			case "oauth":
+				item.OAuth = update.Item.OAuth
+			case "autotype":
+				item.AutoType = update.Item.AutoType
+			case "securityquestions":
+				item.SecurityQuestions = mergeSecurityQuestions(item.SecurityQuestions, update.Item.SecurityQuestions)
 			}
 		}
 
@@ -215,6 +313,74 @@ func (db *Database) UpdateItemsById(updates []ItemUpdate) error {
 			}
 		}
 
+		for _, identityField := range update.IdentityMask {
+			switch strings.ToLower(identityField) {
+			case "documenttype":
+				item.Identity.DocumentType = update.Item.Identity.DocumentType
+			case "number":
+				item.Identity.Number = update.Item.Identity.Number
+			case "issuingauthority":
+				item.Identity.IssuingAuthority = update.Item.Identity.IssuingAuthority
+			case "issuingcountry":
+				item.Identity.IssuingCountry = update.Item.Identity.IssuingCountry
+			case "issuedate":
+				item.Identity.IssueDate = update.Item.Identity.IssueDate
+			case "expirydate":
+				item.Identity.ExpiryDate = update.Item.Identity.ExpiryDate
+			case "holdername":
+				item.Identity.HolderName = update.Item.Identity.HolderName
+			case "dateofbirth":
+				item.Identity.DateOfBirth = update.Item.Identity.DateOfBirth
+			case "photoid":
+				item.Identity.PhotoId = update.Item.Identity.PhotoId
+			}
+		}
+
+		for _, cardField := range update.CardMask {
+			switch strings.ToLower(cardField) {
+			case "cardholder":
+				item.Card.Cardholder = update.Item.Card.Cardholder
+			case "number":
+				item.Card.Number = update.Item.Card.Number
+			case "expirymonth":
+				item.Card.ExpiryMonth = update.Item.Card.ExpiryMonth
+			case "expiryyear":
+				item.Card.ExpiryYear = update.Item.Card.ExpiryYear
+			case "cvv":
+				item.Card.CVV = update.Item.Card.CVV
+			case "pin":
+				item.Card.PIN = update.Item.Card.PIN
+			case "brand":
+				item.Card.Brand = update.Item.Card.Brand
+			case "billingaddress":
+				item.Card.BillingAddress = update.Item.Card.BillingAddress
+			}
+		}
+
+		for _, noteField := range update.NoteMask {
+			switch strings.ToLower(noteField) {
+			case "body":
+				item.Note.Body = update.Item.Note.Body
+			case "format":
+				item.Note.Format = update.Item.Note.Format
+			}
+		}
+
+		for _, sshKeyField := range update.SSHKeyMask {
+			switch strings.ToLower(sshKeyField) {
+			case "privatekey":
+				item.SSHKey.PrivateKey = update.Item.SSHKey.PrivateKey
+			case "publickey":
+				item.SSHKey.PublicKey = update.Item.SSHKey.PublicKey
+			case "comment":
+				item.SSHKey.Comment = update.Item.SSHKey.Comment
+			case "passphrase":
+				item.SSHKey.Passphrase = update.Item.SSHKey.Passphrase
+			case "agent":
+				item.SSHKey.Agent = update.Item.SSHKey.Agent
+			}
+		}
+
 		var groupIds []string
 
 		if update.IncludeGroupIds {
@@ -232,11 +398,32 @@ func (db *Database) UpdateItemsById(updates []ItemUpdate) error {
 			return err
 		}
 
-		err = db.ValidateRuleset(item.Ruleset)
+		err = db.ValidateOAuth(update.ItemId, item.OAuth)
 		if err != nil {
 			return err
 		}
 
+		if item.Type == LOGIN_ITEM {
+			err = db.ValidateRuleset(item.Ruleset)
+			if err != nil {
+				return err
+			}
+
+			if item.TwoFactorSecret != existing.TwoFactorSecret {
+				err = db.ValidateTwoFactorSecret(item.TwoFactorSecret)
+				if err != nil {
+					return err
+				}
+			}
+
+			if item.AutoType != existing.AutoType {
+				err = db.ValidateAutoType(item.AutoType)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		db.SetItem(update.ItemId, item, groupIds, true)
 	}
 
@@ -309,6 +496,7 @@ func (db *Database) SetItem(id string, item Item, groupIds []string, update bool
 
 	item.Type = strings.ToUpper(strings.TrimSpace(item.Type))
 	item.Title = strings.TrimSpace(item.Title)
+	item.CustomFields = normalizeCustomFields(item.CustomFields)
 
 	if item.Type == LOGIN_ITEM {
 		if update {
@@ -317,6 +505,8 @@ func (db *Database) SetItem(id string, item Item, groupIds []string, update bool
 		item.Email = strings.TrimSpace(item.Email)
 		item.TwoFactorSecret = strings.TrimSpace(item.TwoFactorSecret)
 		item.Notes = strings.TrimSpace(item.Notes)
+		item.SecurityQuestions = normalizeSecurityQuestions(item.SecurityQuestions)
+		item.OAuth = normalizeOAuth(item.OAuth)
 
 		websites := []string{}
 		seen := make(map[string]bool)
@@ -334,6 +524,22 @@ func (db *Database) SetItem(id string, item Item, groupIds []string, update bool
 		item.Websites = websites
 	}
 
+	if item.Type == ID_ITEM {
+		item.Identity = normalizeIdentity(item.Identity)
+	}
+
+	if item.Type == BANK_CARD_ITEM {
+		item.Card = normalizeCard(item.Card)
+	}
+
+	if item.Type == NOTE_ITEM {
+		item.Note = normalizeNote(item.Note)
+	}
+
+	if item.Type == SSH_KEY_ITEM {
+		item.SSHKey = normalizeSSHKey(item.SSHKey)
+	}
+
 	db.Items[id] = item
 }
 
@@ -354,11 +560,17 @@ func (db *Database) SetGroup(id string, group Group, update bool) {
 	db.Groups[id] = group
 }
 
-// Deletes an Item
+// Moves Items into the trash, remembering which Groups they belonged to
 func (db *Database) DeleteItemsById(ids []string) error {
+	db.initTrash()
+
+	timestamp := time.Now().Unix()
+
 	for _, id := range ids {
-		_, exists := db.Items[id]
+		item, exists := db.Items[id]
 		if exists {
+			groupIds := []string{}
+
 			for groupId, group := range db.Groups {
 				if slices.Contains(group.Items, id) {
 					newSlice := slices.DeleteFunc(group.Items, func(itemId string) bool {
@@ -366,9 +578,24 @@ func (db *Database) DeleteItemsById(ids []string) error {
 					})
 					group.Items = newSlice
 					db.Groups[groupId] = group
+					groupIds = append(groupIds, groupId)
 				}
 			}
 
+			// Groups already in the trash keep their membership list as-is, but the
+			// item should still find its way back to them if both are restored
+			for groupId, trashed := range db.Trash.Groups {
+				if slices.Contains(trashed.Group.Items, id) {
+					groupIds = append(groupIds, groupId)
+				}
+			}
+
+			db.Trash.Items[id] = TrashedItem{
+				Item:     item,
+				GroupIds: groupIds,
+				Deleted:  timestamp,
+			}
+
 			delete(db.Items, id)
 		}
 	}
@@ -376,10 +603,23 @@ func (db *Database) DeleteItemsById(ids []string) error {
 	return nil
 }
 
-// Deletes a Group
+// Moves Groups into the trash. The Group keeps its Item ids so they can be
+// re-linked on restore.
 func (db *Database) DeleteGroupsById(ids []string) error {
+	db.initTrash()
+
+	timestamp := time.Now().Unix()
+
 	for _, id := range ids {
-		delete(db.Groups, id)
+		group, exists := db.Groups[id]
+		if exists {
+			db.Trash.Groups[id] = TrashedGroup{
+				Group:   group,
+				Deleted: timestamp,
+			}
+
+			delete(db.Groups, id)
+		}
 	}
 
 	return nil
@@ -388,23 +628,72 @@ func (db *Database) DeleteGroupsById(ids []string) error {
 // Validates incoming Item
 func (db *Database) ValidateItem(item Item, groupIds []string, checkTitle, checkReuse bool) error {
 	t := strings.ToUpper(strings.TrimSpace(item.Type))
-	if t != LOGIN_ITEM && t != ID_ITEM && t != BANK_CARD_ITEM && t != NOTE_ITEM {
+	if t != LOGIN_ITEM && t != ID_ITEM && t != BANK_CARD_ITEM && t != NOTE_ITEM && t != SSH_KEY_ITEM {
 		return fmt.Errorf("unknown item")
 	}
 
 	if t == LOGIN_ITEM {
-		// replace the following with the password validator
-		if len(item.Password) == 0 {
+		// replace the following with the password validator. Logins that sign in through an
+		// identity provider don't need a password of their own.
+		if len(item.Password) == 0 && !item.UsesOAuth() {
 			return fmt.Errorf("the password cannot be empty")
 		}
 
+		err := db.ValidateOAuth("", item.OAuth)
+		if err != nil {
+			return err
+		}
+
 		if checkReuse && slices.Contains(item.PrevPasswords, item.Password) {
 			return fmt.Errorf("the item's password has already been used")
 		}
+
+		err = db.ValidateSecurityQuestions(item.SecurityQuestions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t == ID_ITEM {
+		err := db.ValidateIdentity(item.Identity)
+		if err != nil {
+			return err
+		}
+
+		photoId := strings.TrimSpace(item.Identity.PhotoId)
+		if len(photoId) > 0 && !item.HasAttachment(photoId) {
+			return fmt.Errorf("the photo must be one of the item's attachments")
+		}
+	}
+
+	if t == BANK_CARD_ITEM {
+		err := db.ValidateCard(item.Card)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t == NOTE_ITEM {
+		err := db.ValidateNote(item.Note)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t == SSH_KEY_ITEM {
+		err := db.ValidateSSHKey(item.SSHKey)
+		if err != nil {
+			return err
+		}
 	}
 
 	// TODO: Other type checks for minimum required values
 
+	err := db.ValidateCustomFields(item.CustomFields)
+	if err != nil {
+		return err
+	}
+
 	titleUpper := strings.ToUpper(strings.TrimSpace(item.Title))
 	if len(titleUpper) == 0 {
 		return fmt.Errorf("the title cannot be empty")
@@ -426,6 +715,32 @@ func (db *Database) ValidateItem(item Item, groupIds []string, checkTitle, check
 	return nil
 }
 
+// Validates a Login Item's two factor secret. Not part of ValidateItem, since secrets saved
+// before they were validated would make those Items impossible to edit, so it's only checked
+// for new Items and when the secret changes.
+func (db *Database) ValidateTwoFactorSecret(secret string) error {
+	if len(strings.TrimSpace(secret)) == 0 {
+		return nil
+	}
+
+	_, err := twofactor.Parse(secret)
+	if err != nil {
+		return fmt.Errorf("invalid two factor secret: %v", err)
+	}
+
+	return nil
+}
+
+// Validates a Login Item's auto-type sequence. Checked like ValidateTwoFactorSecret.
+func (db *Database) ValidateAutoType(sequence string) error {
+	_, err := autotype.Parse(sequence)
+	if err != nil {
+		return fmt.Errorf("invalid auto-type sequence: %v", err)
+	}
+
+	return nil
+}
+
 // Validates incoming Group
 func (db *Database) ValidateGroup(group Group, checkName bool) error {
 	groupNameUpper := strings.ToUpper(strings.TrimSpace(group.Name))
@@ -501,6 +816,14 @@ func (db *Database) GenerateId() string {
 			continue
 		}
 
+		if _, exists = db.Trash.Items[id]; exists {
+			continue
+		}
+
+		if _, exists = db.Trash.Groups[id]; exists {
+			continue
+		}
+
 		return id
 	}
 }
@@ -550,6 +873,8 @@ type ValidationReport struct {
 	AtMostConstraints  []bool `json:"atMostConstraints"`
 	AtLeastConstraints []bool `json:"atLeastConstraints"`
 	PrevPasswords      bool   `json:"prevPasswords"`
+	Breached           bool   `json:"breached"`    // unlike the rest, true is bad: the password was found in a breach
+	BreachCount        int    `json:"breachCount"` // how many times it was seen in breaches
 }
 
 func StringViolatesSameCharMaxConstraint(s string, scm int) bool {
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+const (
+	PASSWORD_EXPIRY = "PASSWORD"
+	DOCUMENT_EXPIRY = "DOCUMENT"
+	CARD_EXPIRY     = "CARD"
+)
+
+type ExpiryAlert struct {
+	ItemId  string `json:"itemId"`
+	Kind    string `json:"kind"`    // what is expiring, e.g. PASSWORD, DOCUMENT or CARD
+	Expires int64  `json:"expires"` // (unix timestamp)
+	Expired bool   `json:"expired"` // false means it's expiring within the warning period
+}
+
+// Gets the time the Item's password expires at, based on its Ruleset's TTL
+func (item Item) PasswordExpiresAt() time.Time {
+	created := time.Unix(item.PasswordCreated, 0)
+	inc := item.Ruleset.PasswordTTLIncrement
+
+	switch item.Ruleset.PasswordTTLUnit {
+	case 1:
+		return created.AddDate(0, inc, 0)
+	case 2:
+		return created.AddDate(inc, 0, 0)
+	default:
+		return created.AddDate(0, 0, inc)
+	}
+}
+
+// Finds everything across the (non-archived) Items that has expired or will expire within
+// the warning period in the Settings, soonest first
+func (db *Database) GetExpiryAlerts() []ExpiryAlert {
+	now := time.Now()
+	warnAt := now.AddDate(0, 0, db.Settings.ExpiryWarning)
+	alerts := []ExpiryAlert{}
+
+	check := func(id, kind string, expires time.Time) {
+		if expires.IsZero() || expires.After(warnAt) {
+			return
+		}
+
+		alerts = append(alerts, ExpiryAlert{
+			ItemId:  id,
+			Kind:    kind,
+			Expires: expires.Unix(),
+			Expired: !now.Before(expires),
+		})
+	}
+
+	for id, item := range db.Items {
+		if item.Archived {
+			continue
+		}
+
+		switch item.Type {
+		case LOGIN_ITEM:
+			if len(item.Password) > 0 {
+				check(id, PASSWORD_EXPIRY, item.PasswordExpiresAt())
+			}
+		case ID_ITEM:
+			expires, err := parseDate(item.Identity.ExpiryDate, "expiry date")
+			if err == nil {
+				check(id, DOCUMENT_EXPIRY, expires)
+			}
+		case BANK_CARD_ITEM:
+			check(id, CARD_EXPIRY, item.Card.ExpiresAt())
+		}
+	}
+
+	slices.SortFunc(alerts, func(a, b ExpiryAlert) int {
+		return cmp.Compare(a.Expires, b.Expires)
+	})
+
+	return alerts
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The version of the native export format
+const exportVersion = 1
+
+type Export struct {
+	Version  int              `json:"version"`
+	Exported int64            `json:"exported"` // (unix timestamp)
+	Items    map[string]Item  `json:"items"`
+	Groups   map[string]Group `json:"groups"`
+}
+
+// Exports the Items and Groups into Imcrypt's native (unencrypted!) JSON format
+func (db *Database) Export() ([]byte, error) {
+	export := Export{
+		Version:  exportVersion,
+		Exported: time.Now().Unix(),
+		Items:    db.Items,
+		Groups:   db.Groups,
+	}
+
+	return json.MarshalIndent(export, "", "\t")
+}
+
+// Imports Items and Groups from Imcrypt's native JSON format, returning the newly
+// inserted Items' ids. Imported Groups are merged into existing Groups of the same name,
+// and imported Items whose titles are already taken are given a numbered suffix. Links to
+// identity provider Items follow them to their new ids.
+func (db *Database) Import(data []byte) ([]string, error) {
+	var export Export
+
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the export: %v", err)
+	}
+
+	if export.Version < 1 || export.Version > exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+
+	// Maps the exported Groups' ids to their ids in this Database
+	groupIds := make(map[string]string)
+
+	for exportedId, group := range export.Groups {
+		nameUpper := strings.ToUpper(strings.TrimSpace(group.Name))
+
+		for id, exg := range db.Groups {
+			if nameUpper == strings.ToUpper(exg.Name) {
+				groupIds[exportedId] = id
+				break
+			}
+		}
+
+		if _, exists := groupIds[exportedId]; exists {
+			continue
+		}
+
+		group.Items = nil
+
+		ids, err := db.InsertGroups([]Group{group})
+		if err != nil {
+			return nil, err
+		}
+
+		groupIds[exportedId] = ids[0]
+	}
+
+	ids := []string{}
+
+	// Maps the exported Items' ids to their new ids
+	itemIds := make(map[string]string)
+
+	for _, exportedId := range slices.Sorted(maps.Keys(export.Items)) {
+		item := export.Items[exportedId]
+		itemGroupIds := []string{}
+
+		for exportedGroupId, group := range export.Groups {
+			if slices.Contains(group.Items, exportedId) {
+				itemGroupIds = append(itemGroupIds, groupIds[exportedGroupId])
+			}
+		}
+
+		item.Title = db.availableTitle(item.Title)
+
+		// Attachments' contents aren't part of the export
+		item.Attachments = nil
+		item.Identity.PhotoId = ""
+
+		// The provider's Item may not have been imported yet, so links are restored below
+		item.OAuth.ItemId = ""
+
+		inserted, err := db.InsertItems([]InsertItemsArg{{Item: item, GroupIds: itemGroupIds}})
+		if err != nil {
+			return ids, fmt.Errorf("unable to import %s: %v", item.Title, err)
+		}
+
+		ids = append(ids, inserted...)
+		itemIds[exportedId] = inserted[0]
+	}
+
+	// Links to identity provider Items that weren't part of the export are dropped
+	for _, exportedId := range slices.Sorted(maps.Keys(itemIds)) {
+		providerId, exists := itemIds[strings.TrimSpace(export.Items[exportedId].OAuth.ItemId)]
+		if !exists {
+			continue
+		}
+
+		id := itemIds[exportedId]
+		item := db.Items[id]
+		oauth := OAuth{Provider: item.OAuth.Provider, ItemId: providerId}
+
+		if db.ValidateOAuth(id, oauth) != nil {
+			continue
+		}
+
+		item.OAuth = oauth
+		db.Items[id] = item
+	}
+
+	return ids, nil
+}
+
+// Gets the given title, or the first numbered variation of it that isn't already
+// used by another Item
+func (db *Database) availableTitle(title string) string {
+	title = strings.TrimSpace(title)
+	candidate := title
+
+	for i := 2; ; i++ {
+		taken := false
+
+		for _, exi := range db.Items {
+			if strings.EqualFold(candidate, exi.Title) {
+				taken = true
+				break
+			}
+		}
+
+		if !taken {
+			return candidate
+		}
+
+		candidate = fmt.Sprintf("%s (%d)", title, i)
+	}
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"cmp"
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	TEXT_FIELD   = "TEXT"
+	HIDDEN_FIELD = "HIDDEN"
+	URL_FIELD    = "URL"
+	TOTP_FIELD   = "TOTP"
+	DATE_FIELD   = "DATE"
+)
+
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Kind  string `json:"kind"`  // TEXT, HIDDEN, URL, TOTP or DATE
+	Order int    `json:"order"` // position among the Item's custom fields
+}
+
+// Validates an Item's incoming custom fields
+func (db *Database) ValidateCustomFields(fields []Field) error {
+	seen := make(map[string]bool)
+
+	for _, field := range fields {
+		name := strings.TrimSpace(field.Name)
+		if len(name) == 0 {
+			return fmt.Errorf("the custom field name cannot be empty")
+		}
+
+		nameUpper := strings.ToUpper(name)
+		if seen[nameUpper] {
+			return fmt.Errorf("the custom field name %s has already been used in this Item", name)
+		}
+		seen[nameUpper] = true
+
+		value := strings.TrimSpace(field.Value)
+
+		switch strings.ToUpper(strings.TrimSpace(field.Kind)) {
+		case TEXT_FIELD, HIDDEN_FIELD:
+		case URL_FIELD:
+			if len(value) > 0 && !isURL(value) {
+				return fmt.Errorf("the custom field %s must be a valid URL", name)
+			}
+		case TOTP_FIELD:
+			if len(value) > 0 && !isBase32(value) {
+				return fmt.Errorf("the custom field %s must be a base32 TOTP secret", name)
+			}
+		case DATE_FIELD:
+			if _, err := parseDate(value, name); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("the custom field %s must be of kind %s, %s, %s, %s, or %s", name, TEXT_FIELD, HIDDEN_FIELD, URL_FIELD, TOTP_FIELD, DATE_FIELD)
+		}
+	}
+
+	return nil
+}
+
+// Normalizes an Item's custom fields, sorting them by their order and renumbering
+// them from 0
+func normalizeCustomFields(fields []Field) []Field {
+	normalized := slices.Clone(fields)
+
+	slices.SortStableFunc(normalized, func(a, b Field) int {
+		return cmp.Compare(a.Order, b.Order)
+	})
+
+	for i, field := range normalized {
+		field.Name = strings.TrimSpace(field.Name)
+		field.Kind = strings.ToUpper(strings.TrimSpace(field.Kind))
+		field.Order = i
+
+		switch field.Kind {
+		case TOTP_FIELD:
+			field.Value = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(field.Value), " ", ""))
+		case URL_FIELD, DATE_FIELD:
+			field.Value = strings.TrimSpace(field.Value)
+		}
+
+		normalized[i] = field
+	}
+
+	return normalized
+}
+
+// Checks for an absolute URL with a host, like https://example.com. url.Parse alone accepts
+// nearly any string as a relative reference.
+func isURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func isBase32(s string) bool {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(s, "="))
+
+	return err == nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCustomFieldsURL(t *testing.T) {
+	db := NewDatabase()
+
+	valid := []string{
+		"",
+		"https://example.com",
+		"http://example.com:8080/login?next=/home#top",
+		"ftp://files.example.com/pub",
+		"https://user@example.com/",
+		"http://192.168.1.1",
+		"https://[::1]:8443/admin",
+	}
+
+	for _, value := range valid {
+		err := db.ValidateCustomFields([]Field{{Name: "Site", Value: value, Kind: URL_FIELD}})
+		if err != nil {
+			t.Errorf("ValidateCustomFields(%q) failed: %v", value, err)
+		}
+	}
+
+	invalid := []string{
+		"example.com",
+		"not a url",
+		"/relative/path",
+		"//example.com",
+		"https://",
+		"https:///path",
+		"mailto:someone@example.com",
+		"javascript:alert(1)",
+		"http://exa mple.com",
+		"http://example.com/%zz",
+	}
+
+	for _, value := range invalid {
+		err := db.ValidateCustomFields([]Field{{Name: "Site", Value: value, Kind: URL_FIELD}})
+		if err == nil {
+			t.Errorf("ValidateCustomFields(%q) = nil, want an error", value)
+		} else if !strings.Contains(err.Error(), "must be a valid URL") {
+			t.Errorf("ValidateCustomFields(%q) error = %q, want it to be about the URL", value, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	PASSPORT_DOCUMENT       = "PASSPORT"
+	DRIVER_LICENSE_DOCUMENT = "DRIVER_LICENSE"
+	NATIONAL_ID_DOCUMENT    = "NATIONAL_ID"
+)
+
+// The layout used for every calendar date stored on an Item (e.g. 2006-01-02)
+const DateLayout = time.DateOnly
+
+type Identity struct {
+	DocumentType     string `json:"documentType"`     // PASSPORT, DRIVER_LICENSE or NATIONAL_ID
+	Number           string `json:"number"`           // the document number
+	IssuingAuthority string `json:"issuingAuthority"` // e.g. the agency or department that issued it
+	IssuingCountry   string `json:"issuingCountry"`   // ISO 3166-1 alpha-2 country code
+	IssueDate        string `json:"issueDate"`        // DateLayout
+	ExpiryDate       string `json:"expiryDate"`       // DateLayout
+	HolderName       string `json:"holderName"`
+	DateOfBirth      string `json:"dateOfBirth"` // DateLayout
+	PhotoId          string `json:"photoId"`     // id of the Item's attachment holding the holder's photo
+}
+
+// Validates incoming Identity
+func (db *Database) ValidateIdentity(identity Identity) error {
+	documentType := strings.ToUpper(strings.TrimSpace(identity.DocumentType))
+	if documentType != PASSPORT_DOCUMENT && documentType != DRIVER_LICENSE_DOCUMENT && documentType != NATIONAL_ID_DOCUMENT {
+		return fmt.Errorf("the document type must be %s, %s, or %s", PASSPORT_DOCUMENT, DRIVER_LICENSE_DOCUMENT, NATIONAL_ID_DOCUMENT)
+	}
+
+	if len(strings.TrimSpace(identity.Number)) == 0 {
+		return fmt.Errorf("the document number cannot be empty")
+	}
+
+	country := strings.TrimSpace(identity.IssuingCountry)
+	if len(country) > 0 && !isCountryCode(country) {
+		return fmt.Errorf("the issuing country must be a 2-letter country code")
+	}
+
+	issued, err := parseDate(identity.IssueDate, "issue date")
+	if err != nil {
+		return err
+	}
+
+	expires, err := parseDate(identity.ExpiryDate, "expiry date")
+	if err != nil {
+		return err
+	}
+
+	born, err := parseDate(identity.DateOfBirth, "date of birth")
+	if err != nil {
+		return err
+	}
+
+	if !issued.IsZero() && !expires.IsZero() && !expires.After(issued) {
+		return fmt.Errorf("the expiry date must be after the issue date")
+	}
+
+	if !born.IsZero() && born.After(time.Now()) {
+		return fmt.Errorf("the date of birth cannot be in the future")
+	}
+
+	if !born.IsZero() && !issued.IsZero() && issued.Before(born) {
+		return fmt.Errorf("the issue date cannot be before the date of birth")
+	}
+
+	return nil
+}
+
+// Normalizes an Identity's data. Dates are expected to have been validated already.
+func normalizeIdentity(identity Identity) Identity {
+	identity.DocumentType = strings.ToUpper(strings.TrimSpace(identity.DocumentType))
+	identity.Number = strings.ToUpper(strings.Join(strings.Fields(identity.Number), ""))
+	identity.IssuingAuthority = strings.TrimSpace(identity.IssuingAuthority)
+	identity.IssuingCountry = strings.ToUpper(strings.TrimSpace(identity.IssuingCountry))
+	identity.IssueDate = strings.TrimSpace(identity.IssueDate)
+	identity.ExpiryDate = strings.TrimSpace(identity.ExpiryDate)
+	identity.HolderName = strings.TrimSpace(identity.HolderName)
+	identity.DateOfBirth = strings.TrimSpace(identity.DateOfBirth)
+	identity.PhotoId = strings.TrimSpace(identity.PhotoId)
+
+	return identity
+}
+
+// Parses an optional DateLayout date, returning the zero time if it's empty
+func parseDate(s, name string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the %s must be formatted as YYYY-MM-DD", name)
+	}
+
+	return t, nil
+}
+
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	PLAIN_NOTE_FORMAT    = "PLAIN"
+	MARKDOWN_NOTE_FORMAT = "MARKDOWN"
+)
+
+// A single note may use at most 1/noteBudgetShare of the image's capacity, leaving
+// room for everything else in the Database
+const noteBudgetShare = 4
+
+type Note struct {
+	Body   string `json:"body"`   // kept verbatim, whitespace included
+	Format string `json:"format"` // PLAIN or MARKDOWN, how the body should be rendered
+}
+
+// Validates incoming Note
+func (db *Database) ValidateNote(note Note) error {
+	format := strings.ToUpper(strings.TrimSpace(note.Format))
+	if format != "" && format != PLAIN_NOTE_FORMAT && format != MARKDOWN_NOTE_FORMAT {
+		return fmt.Errorf("the note format must be %s or %s", PLAIN_NOTE_FORMAT, MARKDOWN_NOTE_FORMAT)
+	}
+
+	if !utf8.ValidString(note.Body) {
+		return fmt.Errorf("the note body must be valid UTF-8 text")
+	}
+
+	return nil
+}
+
+// Validates that every Note fits within its share of the given capacity (in bytes)
+func (db *Database) ValidateNoteBudget(capacity int) error {
+	budget := NoteBudget(capacity)
+
+	for _, item := range db.Items {
+		if item.Type == NOTE_ITEM && len(item.Note.Body) > budget {
+			return fmt.Errorf("the note %s is %d bytes, exceeding the loaded image's budget of %d bytes per note", item.Title, len(item.Note.Body), budget)
+		}
+	}
+
+	return nil
+}
+
+// Gets the maximum size of a single note's body (in bytes) for the given capacity
+func NoteBudget(capacity int) int {
+	return capacity / noteBudgetShare
+}
+
+// Normalizes a Note's data. The body is deliberately left untouched.
+func normalizeNote(note Note) Note {
+	note.Format = strings.ToUpper(strings.TrimSpace(note.Format))
+
+	if len(note.Format) == 0 {
+		note.Format = PLAIN_NOTE_FORMAT
+	}
+
+	return note
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A login that signs in through an identity provider ("Sign in with Google", etc.) instead
+// of with its own password
+type OAuth struct {
+	Provider string `json:"provider"` // e.g. Google, GitHub, Microsoft. Empty means not in use.
+	ItemId   string `json:"itemId"`   // the Item holding the provider's credentials, if stored
+}
+
+// Checks if the Item signs in through an identity provider
+func (item Item) UsesOAuth() bool {
+	return len(strings.TrimSpace(item.OAuth.Provider)) > 0
+}
+
+// Validates an Item's incoming OAuth link. The id is the Item's own id, or an empty string
+// if it's being inserted.
+func (db *Database) ValidateOAuth(id string, oauth OAuth) error {
+	providerItemId := strings.TrimSpace(oauth.ItemId)
+
+	if len(providerItemId) == 0 {
+		return nil
+	}
+
+	if len(strings.TrimSpace(oauth.Provider)) == 0 {
+		return fmt.Errorf("the identity provider cannot be empty when linking to its Item")
+	}
+
+	// Walk the chain of providers (e.g. a provider that itself signs in with another
+	// provider) to make sure it doesn't lead back to this Item
+	seen := map[string]bool{id: true}
+
+	for next := providerItemId; len(next) > 0; {
+		if seen[next] {
+			return fmt.Errorf("the identity provider's Item cannot sign in through this Item")
+		}
+		seen[next] = true
+
+		provider, exists := db.Items[next]
+		if !exists {
+			trashed, exists := db.Trash.Items[next]
+			if !exists {
+				return fmt.Errorf("cannot find Item with id %s", next)
+			}
+
+			provider = trashed.Item
+		}
+
+		if next == providerItemId && provider.Type != LOGIN_ITEM {
+			return fmt.Errorf("the identity provider's Item must be a login")
+		}
+
+		next = provider.OAuth.ItemId
+	}
+
+	return nil
+}
+
+// Finds every Item that signs in through the given provider Item
+func (db *Database) FindOAuthDependents(providerItemId string) []string {
+	dependents := []string{}
+
+	for id, item := range db.Items {
+		if item.OAuth.ItemId == providerItemId {
+			dependents = append(dependents, id)
+		}
+	}
+
+	slices.Sort(dependents)
+
+	return dependents
+}
+
+// Removes any links to the given provider Item, for when it's gone for good
+func (db *Database) unlinkOAuth(providerItemId string) {
+	for id, item := range db.Items {
+		if item.OAuth.ItemId == providerItemId {
+			item.OAuth.ItemId = ""
+			db.Items[id] = item
+		}
+	}
+
+	for id, trashed := range db.Trash.Items {
+		if trashed.Item.OAuth.ItemId == providerItemId {
+			trashed.Item.OAuth.ItemId = ""
+			db.Trash.Items[id] = trashed
+		}
+	}
+}
+
+func normalizeOAuth(oauth OAuth) OAuth {
+	oauth.Provider = strings.TrimSpace(oauth.Provider)
+	oauth.ItemId = strings.TrimSpace(oauth.ItemId)
+
+	return oauth
+}
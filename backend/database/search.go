@@ -0,0 +1,70 @@
+package database
+
+import (
+	"slices"
+	"strings"
+)
+
+// Searches the Items' text (never their secrets) for the given query, case-insensitively,
+// returning the matching ids. Title matches are listed before content matches.
+func (db *Database) SearchItems(query string) []string {
+	q := strings.ToLower(strings.TrimSpace(query))
+	titleMatches := []string{}
+	contentMatches := []string{}
+
+	if len(q) == 0 {
+		return titleMatches
+	}
+
+	for id, item := range db.Items {
+		if strings.Contains(strings.ToLower(item.Title), q) {
+			titleMatches = append(titleMatches, id)
+			continue
+		}
+
+		for _, text := range item.searchableText() {
+			if strings.Contains(strings.ToLower(text), q) {
+				contentMatches = append(contentMatches, id)
+				break
+			}
+		}
+	}
+
+	byTitle := func(a, b string) int {
+		return strings.Compare(strings.ToLower(db.Items[a].Title), strings.ToLower(db.Items[b].Title))
+	}
+
+	slices.SortFunc(titleMatches, byTitle)
+	slices.SortFunc(contentMatches, byTitle)
+
+	return append(titleMatches, contentMatches...)
+}
+
+// Gets the non-secret text of an Item that can be searched
+func (item Item) searchableText() []string {
+	text := []string{}
+
+	switch item.Type {
+	case LOGIN_ITEM:
+		text = append(text, item.Email, item.Username, item.Notes, item.OAuth.Provider)
+		text = append(text, item.Websites...)
+	case ID_ITEM:
+		text = append(text, item.Identity.HolderName, item.Identity.IssuingAuthority, item.Identity.IssuingCountry)
+	case BANK_CARD_ITEM:
+		text = append(text, item.Card.Cardholder, item.Card.Brand)
+	case NOTE_ITEM:
+		text = append(text, item.Note.Body)
+	case SSH_KEY_ITEM:
+		text = append(text, item.SSHKey.Comment, item.SSHKey.Fingerprint)
+	}
+
+	for _, field := range item.CustomFields {
+		text = append(text, field.Name)
+
+		if field.Kind != HIDDEN_FIELD && field.Kind != TOTP_FIELD {
+			text = append(text, field.Value)
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+type QA struct {
+	Id        string  `json:"id"`
+	Question  string  `json:"question"`
+	Answer    string  `json:"answer"`    // blanked in API responses, see Redacted
+	HasAnswer bool    `json:"hasAnswer"` // set in API responses, where the answer is hidden, and sent back to keep it
+	Ruleset   Ruleset `json:"ruleset"`   // used when generating a random answer
+}
+
+// Validates an Item's incoming security questions
+func (db *Database) ValidateSecurityQuestions(questions []QA) error {
+	seen := make(map[string]bool)
+
+	for _, qa := range questions {
+		question := strings.TrimSpace(qa.Question)
+		if len(question) == 0 {
+			return fmt.Errorf("the security question cannot be empty")
+		}
+
+		questionUpper := strings.ToUpper(question)
+		if seen[questionUpper] {
+			return fmt.Errorf("the security question %s has already been used in this Item", question)
+		}
+		seen[questionUpper] = true
+	}
+
+	return nil
+}
+
+// Finds security answers that are shared by more than one Item, returning the ids of
+// the Items sharing each one
+func (db *Database) FindReusedSecurityAnswers() [][]string {
+	owners := make(map[string][]string)
+
+	for id, item := range db.Items {
+		for _, qa := range item.SecurityQuestions {
+			answer := strings.ToUpper(strings.TrimSpace(qa.Answer))
+
+			if len(answer) > 0 && !slices.Contains(owners[answer], id) {
+				owners[answer] = append(owners[answer], id)
+			}
+		}
+	}
+
+	reused := [][]string{}
+
+	for _, ids := range owners {
+		if len(ids) > 1 {
+			slices.Sort(ids)
+			reused = append(reused, ids)
+		}
+	}
+
+	return reused
+}
+
+// Gets every security answer stored across the Items, except for the given Item's
+func (db *Database) SecurityAnswers(exceptItemId string) []string {
+	answers := []string{}
+
+	for id, item := range db.Items {
+		if id == exceptItemId {
+			continue
+		}
+
+		for _, qa := range item.SecurityQuestions {
+			if len(qa.Answer) > 0 {
+				answers = append(answers, qa.Answer)
+			}
+		}
+	}
+
+	return answers
+}
+
+// Finds a security question on an Item
+func (db *Database) GetSecurityQuestion(itemId, questionId string) (QA, error) {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return QA{}, fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	for _, qa := range item.SecurityQuestions {
+		if qa.Id == questionId {
+			return qa, nil
+		}
+	}
+
+	return QA{}, fmt.Errorf("cannot find security question with id %s", questionId)
+}
+
+// Sets the answer of a security question on an Item
+func (db *Database) SetSecurityAnswer(itemId, questionId, answer string) error {
+	item, exists := db.Items[itemId]
+	if !exists {
+		return fmt.Errorf("cannot find Item with id %s", itemId)
+	}
+
+	questions := slices.Clone(item.SecurityQuestions)
+
+	i := slices.IndexFunc(questions, func(qa QA) bool {
+		return qa.Id == questionId
+	})
+	if i < 0 {
+		return fmt.Errorf("cannot find security question with id %s", questionId)
+	}
+
+	questions[i].Answer = answer
+	item.SecurityQuestions = questions
+	db.Items[itemId] = item
+
+	return nil
+}
+
+// Creates a copy of the Database that is safe to hand to the frontend, with every
+// security answer hidden
+func (db *Database) Redacted() *Database {
+	redacted := *db
+	redacted.Items = make(map[string]Item, len(db.Items))
+	redacted.Trash.Items = make(map[string]TrashedItem, len(db.Trash.Items))
+
+	for id, item := range db.Items {
+		redacted.Items[id] = redactItem(item)
+	}
+
+	for id, trashed := range db.Trash.Items {
+		trashed.Item = redactItem(trashed.Item)
+		redacted.Trash.Items[id] = trashed
+	}
+
+	return &redacted
+}
+
+func redactItem(item Item) Item {
+	if len(item.SecurityQuestions) == 0 {
+		return item
+	}
+
+	questions := slices.Clone(item.SecurityQuestions)
+
+	for i := range questions {
+		questions[i].HasAnswer = len(questions[i].Answer) > 0
+		questions[i].Answer = ""
+	}
+
+	item.SecurityQuestions = questions
+
+	return item
+}
+
+// Merges incoming security questions with the existing ones. Since answers are hidden
+// from the frontend, an incoming question with a known id, an empty answer and HasAnswer
+// still set (as it was handed out) keeps its existing answer. Without HasAnswer, the answer
+// is cleared.
+func mergeSecurityQuestions(existing, incoming []QA) []QA {
+	merged := slices.Clone(incoming)
+
+	for i, qa := range merged {
+		if len(qa.Answer) > 0 || len(qa.Id) == 0 || !qa.HasAnswer {
+			continue
+		}
+
+		for _, exqa := range existing {
+			if exqa.Id == qa.Id {
+				merged[i].Answer = exqa.Answer
+				break
+			}
+		}
+	}
+
+	return merged
+}
+
+// Normalizes an Item's security questions, giving new questions an id
+func normalizeSecurityQuestions(questions []QA) []QA {
+	normalized := slices.Clone(questions)
+	ids := make(map[string]bool)
+
+	for i, qa := range normalized {
+		qa.Question = strings.TrimSpace(qa.Question)
+		qa.HasAnswer = false
+
+		for len(qa.Id) == 0 || ids[qa.Id] {
+			qa.Id, _ = gonanoid.New(6)
+		}
+		ids[qa.Id] = true
+
+		normalized[i] = qa
+	}
+
+	return normalized
+}
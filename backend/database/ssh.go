@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+	"imcrypt_v3/backend/sshkey"
+	"strings"
+)
+
+type SSHKey struct {
+	PrivateKey  string `json:"privateKey"` // OpenSSH (or PEM) formatted
+	PublicKey   string `json:"publicKey"`  // authorized_keys formatted, derived from the private key if left empty
+	Comment     string `json:"comment"`
+	Passphrase  string `json:"passphrase"`  // decrypts the private key, if it's encrypted
+	Fingerprint string `json:"fingerprint"` // SHA256 fingerprint of the public key, set on save
+	Agent       bool   `json:"agent"`       // serve this key through the ssh-agent
+}
+
+// Validates incoming SSHKey
+func (db *Database) ValidateSSHKey(key SSHKey) error {
+	if len(strings.TrimSpace(key.PrivateKey)) == 0 {
+		return fmt.Errorf("the private key cannot be empty")
+	}
+
+	derived, err := sshkey.PublicKeyOf(key.PrivateKey, key.Passphrase, "")
+	if err != nil {
+		return err
+	}
+
+	if len(strings.TrimSpace(key.PublicKey)) > 0 && !sshkey.SamePublicKey(derived, key.PublicKey) {
+		return fmt.Errorf("the public key doesn't belong to the private key")
+	}
+
+	return nil
+}
+
+// Normalizes an SSHKey's data. The key is expected to have been validated already.
+func normalizeSSHKey(key SSHKey) SSHKey {
+	key.PrivateKey = strings.TrimSpace(key.PrivateKey) + "\n"
+	key.Comment = strings.TrimSpace(key.Comment)
+
+	if publicKey, err := sshkey.PublicKeyOf(key.PrivateKey, key.Passphrase, key.Comment); err == nil {
+		key.PublicKey = publicKey
+	}
+
+	if fingerprint, err := sshkey.Fingerprint(key.PublicKey); err == nil {
+		key.Fingerprint = fingerprint
+	}
+
+	return key
+}
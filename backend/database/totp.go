@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"imcrypt_v3/backend/twofactor"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Generates the one-time code for a Login Item's two factor secret. HOTP secrets have their
+// counter advanced, so the same code is never handed out twice.
+func (db *Database) GenerateTOTP(itemId string, t time.Time) (twofactor.Code, error) {
+	item, exists := db.Items[itemId]
+	if !exists || item.Type != LOGIN_ITEM {
+		return twofactor.Code{}, fmt.Errorf("cannot find Login Item with id %s", itemId)
+	}
+
+	if len(item.TwoFactorSecret) == 0 {
+		return twofactor.Code{}, fmt.Errorf("the item doesn't have a two factor secret")
+	}
+
+	params, err := twofactor.Parse(item.TwoFactorSecret)
+	if err != nil {
+		return twofactor.Code{}, err
+	}
+
+	code, err := params.Generate(t)
+	if err != nil {
+		return twofactor.Code{}, err
+	}
+
+	if params.Kind == twofactor.HOTP_KIND {
+		params.Counter++
+		item.TwoFactorSecret = params.URI()
+		db.Items[itemId] = item
+	}
+
+	return code, nil
+}
+
+// Fills in the two factor secret of the Login Item each entry belongs to. An entry belongs to
+// the only Login Item without a secret whose title or website matches its issuer (and whose
+// email or username matches its account, if there are several). Returns the ids of the
+// updated Items, and the entries that couldn't be matched.
+func (db *Database) MigrateTwoFactorSecrets(entries []twofactor.Entry) ([]string, []twofactor.Entry) {
+	updated := []string{}
+	unmatched := []twofactor.Entry{}
+	timestamp := time.Now().Unix()
+
+	for _, entry := range entries {
+		candidates := []string{}
+
+		for id, item := range db.Items {
+			if item.Type == LOGIN_ITEM && len(item.TwoFactorSecret) == 0 && item.matchesIssuer(entry.Params.Issuer) {
+				candidates = append(candidates, id)
+			}
+		}
+
+		if len(candidates) > 1 {
+			candidates = slices.DeleteFunc(candidates, func(id string) bool {
+				return !db.Items[id].matchesAccount(entry.Params.Account)
+			})
+		}
+
+		if len(candidates) != 1 {
+			unmatched = append(unmatched, entry)
+			continue
+		}
+
+		item := db.Items[candidates[0]]
+		item.TwoFactorSecret = entry.URI
+		item.Updated = timestamp
+		db.Items[candidates[0]] = item
+
+		updated = append(updated, candidates[0])
+	}
+
+	return updated, unmatched
+}
+
+func (item Item) matchesIssuer(issuer string) bool {
+	issuer = strings.ToLower(strings.TrimSpace(issuer))
+	if len(issuer) == 0 {
+		return false
+	}
+
+	if strings.ToLower(item.Title) == issuer {
+		return true
+	}
+
+	for _, website := range item.Websites {
+		if strings.Contains(strings.ToLower(website), strings.ReplaceAll(issuer, " ", "")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (item Item) matchesAccount(account string) bool {
+	account = strings.TrimSpace(account)
+
+	return len(account) > 0 && (strings.EqualFold(item.Email, account) || strings.EqualFold(item.Username, account))
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type TrashedItem struct {
+	Item     Item     `json:"item"`
+	GroupIds []string `json:"groupIds"` // the Groups the Item belonged to when it was deleted
+	Deleted  int64    `json:"deleted"`  // (unix timestamp)
+}
+
+type TrashedGroup struct {
+	Group   Group `json:"group"`
+	Deleted int64 `json:"deleted"` // (unix timestamp)
+}
+
+type Trash struct {
+	Items  map[string]TrashedItem  `json:"items"`
+	Groups map[string]TrashedGroup `json:"groups"`
+}
+
+// Restores Items from the trash, re-linking them to any of their former Groups
+// that still exist (including Groups that are themselves in the trash)
+func (db *Database) RestoreItems(ids []string) error {
+	db.initTrash()
+
+	for _, id := range ids {
+		trashed, exists := db.Trash.Items[id]
+		if !exists {
+			return fmt.Errorf("cannot find trashed Item with id %s", id)
+		}
+
+		err := db.ValidateItem(trashed.Item, nil, true, false)
+		if err != nil {
+			return err
+		}
+
+		for _, groupId := range trashed.GroupIds {
+			if group, exists := db.Groups[groupId]; exists {
+				if !slices.Contains(group.Items, id) {
+					group.Items = append(group.Items, id)
+					db.Groups[groupId] = group
+				}
+			} else if trashedGroup, exists := db.Trash.Groups[groupId]; exists {
+				if !slices.Contains(trashedGroup.Group.Items, id) {
+					trashedGroup.Group.Items = append(trashedGroup.Group.Items, id)
+					db.Trash.Groups[groupId] = trashedGroup
+				}
+			}
+		}
+
+		db.Items[id] = trashed.Item
+
+		delete(db.Trash.Items, id)
+	}
+
+	return nil
+}
+
+// Restores Groups from the trash. Items that are still in the trash are left out
+// until they are restored themselves.
+func (db *Database) RestoreGroups(ids []string) error {
+	db.initTrash()
+
+	for _, id := range ids {
+		trashed, exists := db.Trash.Groups[id]
+		if !exists {
+			return fmt.Errorf("cannot find trashed Group with id %s", id)
+		}
+
+		group := trashed.Group
+		group.Items = slices.DeleteFunc(slices.Clone(group.Items), func(itemId string) bool {
+			_, exists := db.Items[itemId]
+			return !exists
+		})
+
+		err := db.ValidateGroup(group, true)
+		if err != nil {
+			return err
+		}
+
+		db.Groups[id] = group
+
+		delete(db.Trash.Groups, id)
+	}
+
+	return nil
+}
+
+// Permanently deletes everything in the trash
+func (db *Database) EmptyTrash() {
+	for id := range db.Trash.Items {
+		db.unlinkOAuth(id)
+	}
+
+	db.Trash = Trash{
+		Items:  make(map[string]TrashedItem),
+		Groups: make(map[string]TrashedGroup),
+	}
+}
+
+// Permanently deletes anything that has been in the trash for longer than the
+// retention period in the Settings, returning how many entities were purged
+func (db *Database) PurgeTrash() int {
+	db.initTrash()
+
+	if db.Settings.TrashRetention <= 0 {
+		return 0
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -db.Settings.TrashRetention).Unix()
+	purged := 0
+
+	for id, trashed := range db.Trash.Items {
+		if trashed.Deleted < cutoff {
+			delete(db.Trash.Items, id)
+			db.unlinkOAuth(id)
+			purged++
+		}
+	}
+
+	for id, trashed := range db.Trash.Groups {
+		if trashed.Deleted < cutoff {
+			delete(db.Trash.Groups, id)
+			purged++
+		}
+	}
+
+	return purged
+}
+
+// Databases created before the trash existed won't have its maps initialized
+func (db *Database) initTrash() {
+	if db.Trash.Items == nil {
+		db.Trash.Items = make(map[string]TrashedItem)
+	}
+
+	if db.Trash.Groups == nil {
+		db.Trash.Groups = make(map[string]TrashedGroup)
+	}
+}
@@ -0,0 +1,129 @@
+package fido2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var ErrNoDevice = errors.New("no FIDO2 security key was found, plug one in and try again")
+
+// The relying party Imcrypt's credentials are made for
+const relyingParty = "imcrypt"
+
+// A FIDO2 security key, driven through libfido2's command line tools (fido2-token, fido2-cred
+// and fido2-assert). Each request waits for the key to be touched.
+type Device struct {
+	Path string
+}
+
+// Finds the first security key that's plugged in
+func Find() (*Device, error) {
+	out, err := run("fido2-token", nil, "-L")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each line is "<path>: <vendor and product>"
+	for _, line := range strings.Split(out, "\n") {
+		path, _, found := strings.Cut(line, ": ")
+		if found && len(strings.TrimSpace(path)) > 0 {
+			return &Device{Path: strings.TrimSpace(path)}, nil
+		}
+	}
+
+	return nil, ErrNoDevice
+}
+
+// Makes a credential with the hmac-secret extension, returning its id. The user is only there
+// because the authenticator needs one.
+func (d *Device) MakeCredential(userName string) ([]byte, error) {
+	clientDataHash, err := random(32)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := random(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Client data hash, relying party, user name and user id
+	input := strings.Join([]string{clientDataHash, relyingParty, userName, userId}, "\n") + "\n"
+
+	out, err := run("fido2-cred", []byte(input), "-M", "-h", d.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Client data hash, relying party, format, authenticator data, credential id, ...
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 5 {
+		return nil, fmt.Errorf("unexpected output from fido2-cred")
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(lines[4]))
+}
+
+// Gets the credential's HMAC of the salt, which must be 32 bytes, from the authenticator. It
+// implements storage.Authenticator.
+func (d *Device) HMACSecret(credentialId, salt []byte) ([]byte, error) {
+	clientDataHash, err := random(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Client data hash, relying party, credential id and hmac salt
+	input := strings.Join([]string{
+		clientDataHash,
+		relyingParty,
+		base64.StdEncoding.EncodeToString(credentialId),
+		base64.StdEncoding.EncodeToString(salt),
+	}, "\n") + "\n"
+
+	out, err := run("fido2-assert", []byte(input), "-G", "-h", d.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hmac secret comes last, after the assertion itself
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 5 {
+		return nil, fmt.Errorf("unexpected output from fido2-assert")
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+}
+
+// Runs one of libfido2's tools, feeding it the input on stdin
+func run(name string, input []byte, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("%s wasn't found, install libfido2's tools to use a security key", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+func random(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -2,6 +2,7 @@ package file
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"imcrypt_v3/backend/storage"
@@ -14,6 +15,11 @@ import (
 	"github.com/DimitarPetrov/stegify/steg"
 )
 
+var ErrInsufficientCapacity = errors.New("the storage is too large to fit in the image")
+
+// The pixels stegify reserves at the start of the image for the data size header
+const stegHeaderPixels = 5
+
 type File struct {
 	*os.File
 	Path string
@@ -87,6 +93,16 @@ func (f *File) WriteImcryptStorage(storage *storage.Storage) error {
 		return err
 	}
 
+	// Check before truncating, otherwise a failed encode would leave nothing behind
+	capacity, err := capacityOf(bytes.NewReader(dataBuf))
+	if err != nil {
+		return err
+	}
+
+	if len(gobifiedStorage) > capacity {
+		return ErrInsufficientCapacity
+	}
+
 	f.Truncate(0)
 	f.Seek(0, 0)
 
@@ -123,6 +139,31 @@ func (f *File) HasStorage() (bool, error) {
 	return true, nil
 }
 
+// Gets how many bytes of storage the image can hold
+func (f *File) Capacity() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Seek(0, 0)
+
+	return capacityOf(f)
+}
+
+// Each pixel past the header holds a quarter of a byte in each of its R, G and B channels
+func capacityOf(r io.Reader) (int, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, err
+	}
+
+	pixels := config.Width*config.Height - stegHeaderPixels
+	if pixels < 0 {
+		return 0, nil
+	}
+
+	return pixels * 3 / 4, nil
+}
+
 // Get's the file's filename
 func (f *File) GetName() string {
 	return filepath.Base(f.Path)
@@ -35,3 +35,21 @@ func OpenFile(path string) (*file.File, error) {
 
 	return &file.File{File: f, Path: path}, nil
 }
+
+// Prompts the user to select where to save a file
+func SaveFileDialog(ctx context.Context, title, defaultFilename string, filters []runtime.FileFilter) (string, error) {
+	absolutePath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+		Title:           title,
+		DefaultFilename: defaultFilename,
+		Filters:         filters,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(absolutePath) == 0 {
+		return "", errors.New("user cancelled the selection")
+	}
+
+	return absolutePath, nil
+}
@@ -0,0 +1,218 @@
+package history
+
+import (
+	"errors"
+	"imcrypt_v3/backend/database"
+	"reflect"
+	"sync"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// The maximum number of commands kept on either stack
+const limit = 100
+
+// The state of every entity touched by a command. A nil value means the entity
+// didn't exist.
+type Patch struct {
+	Items         map[string]*database.Item
+	Groups        map[string]*database.Group
+	TrashedItems  map[string]*database.TrashedItem
+	TrashedGroups map[string]*database.TrashedGroup
+	Settings      *database.Settings
+}
+
+// A recorded mutation. Applying Before undoes it, applying After redoes it.
+type Command struct {
+	Name   string
+	Before Patch
+	After  Patch
+}
+
+type Stack struct {
+	undo     []Command
+	redo     []Command
+	counters map[string]int64 // the highest counter seen for each HOTP secret, see usage.go
+	mu       sync.Mutex
+}
+
+// Creates a new Stack
+func NewStack() *Stack {
+	return &Stack{}
+}
+
+// Records the difference between the before and after states of a mutation. Recording a
+// new command discards anything that was previously undone.
+func (s *Stack) Record(name string, before, after *database.Database) {
+	before, after = withTrash(before), withTrash(after)
+
+	cmd := Command{
+		Name: name,
+		Before: Patch{
+			Items:         diff(before.Items, after.Items),
+			Groups:        diff(before.Groups, after.Groups),
+			TrashedItems:  diff(before.Trash.Items, after.Trash.Items),
+			TrashedGroups: diff(before.Trash.Groups, after.Trash.Groups),
+		},
+		After: Patch{
+			Items:         diff(after.Items, before.Items),
+			Groups:        diff(after.Groups, before.Groups),
+			TrashedItems:  diff(after.Trash.Items, before.Trash.Items),
+			TrashedGroups: diff(after.Trash.Groups, before.Trash.Groups),
+		},
+	}
+
+	if !reflect.DeepEqual(before.Settings, after.Settings) {
+		b, a := before.Settings, after.Settings
+		cmd.Before.Settings = &b
+		cmd.After.Settings = &a
+	}
+
+	if cmd.Before.isEmpty() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.undo = push(s.undo, cmd)
+	s.redo = nil
+}
+
+// Reverts the most recent command on the given Database, returning its name
+func (s *Stack) Undo(db *database.Database) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.undo) == 0 {
+		return "", ErrNothingToUndo
+	}
+
+	cmd := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+
+	lastUsed := s.observe(db)
+	cmd.Before.apply(db)
+	s.keepUsage(db, lastUsed)
+	s.redo = push(s.redo, cmd)
+
+	return cmd.Name, nil
+}
+
+// Re-applies the most recently undone command on the given Database, returning its name
+func (s *Stack) Redo(db *database.Database) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.redo) == 0 {
+		return "", ErrNothingToRedo
+	}
+
+	cmd := s.redo[len(s.redo)-1]
+	s.redo = s.redo[:len(s.redo)-1]
+
+	lastUsed := s.observe(db)
+	cmd.After.apply(db)
+	s.keepUsage(db, lastUsed)
+	s.undo = push(s.undo, cmd)
+
+	return cmd.Name, nil
+}
+
+// Checks if there is anything to undo or redo
+func (s *Stack) Status() (canUndo, canRedo bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.undo) > 0, len(s.redo) > 0
+}
+
+// Discards all recorded commands
+func (s *Stack) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.undo = nil
+	s.redo = nil
+	s.counters = nil
+}
+
+// Writes every entity in the patch onto the given Database
+func (p Patch) apply(db *database.Database) {
+	db.Trash = withTrash(db).Trash
+
+	set(db.Items, p.Items)
+	set(db.Groups, p.Groups)
+	set(db.Trash.Items, p.TrashedItems)
+	set(db.Trash.Groups, p.TrashedGroups)
+
+	if p.Settings != nil {
+		db.Settings = *p.Settings
+	}
+}
+
+func (p Patch) isEmpty() bool {
+	return len(p.Items) == 0 && len(p.Groups) == 0 && len(p.TrashedItems) == 0 && len(p.TrashedGroups) == 0 && p.Settings == nil
+}
+
+// Collects the values in a that differ from b, keyed by id. Ids missing from a are
+// recorded as nil.
+func diff[T any](a, b map[string]T) map[string]*T {
+	res := make(map[string]*T)
+
+	for id, av := range a {
+		if bv, exists := b[id]; !exists || !reflect.DeepEqual(av, bv) {
+			res[id] = &av
+		}
+	}
+
+	for id := range b {
+		if _, exists := a[id]; !exists {
+			res[id] = nil
+		}
+	}
+
+	return res
+}
+
+func set[T any](m map[string]T, values map[string]*T) {
+	for id, v := range values {
+		if v == nil {
+			delete(m, id)
+		} else {
+			m[id] = *v
+		}
+	}
+}
+
+func push(s []Command, cmd Command) []Command {
+	s = append(s, cmd)
+
+	if len(s) > limit {
+		s = s[len(s)-limit:]
+	}
+
+	return s
+}
+
+// Databases created before the trash existed won't have its maps initialized
+func withTrash(db *database.Database) *database.Database {
+	if db.Trash.Items == nil || db.Trash.Groups == nil {
+		c := *db
+
+		if c.Trash.Items == nil {
+			c.Trash.Items = make(map[string]database.TrashedItem)
+		}
+
+		if c.Trash.Groups == nil {
+			c.Trash.Groups = make(map[string]database.TrashedGroup)
+		}
+
+		return &c
+	}
+
+	return db
+}
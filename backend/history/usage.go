@@ -0,0 +1,77 @@
+package history
+
+import (
+	"imcrypt_v3/backend/database"
+	"imcrypt_v3/backend/twofactor"
+)
+
+// Using an Item (copying a field, auto-typing it or generating a one-time code) isn't recorded
+// as a command, so what it leaves behind has to survive undoing and redoing around it.
+// Otherwise undoing an earlier edit would roll back HOTP counters, handing out used codes again.
+
+// Notes the usage in the Database before a patch is applied: the highest counter of every
+// HOTP secret, which is kept for the whole session, and when each Item was last used. Expects
+// the Stack to be locked.
+func (s *Stack) observe(db *database.Database) map[string]int64 {
+	if s.counters == nil {
+		s.counters = make(map[string]int64)
+	}
+
+	lastUsed := make(map[string]int64)
+
+	note := func(id string, item database.Item) {
+		lastUsed[id] = item.LastUsed
+
+		if params, ok := hotp(item); ok {
+			s.counters[params.Secret] = max(s.counters[params.Secret], params.Counter)
+		}
+	}
+
+	for id, item := range db.Items {
+		note(id, item)
+	}
+
+	for id, trashed := range db.Trash.Items {
+		note(id, trashed.Item)
+	}
+
+	return lastUsed
+}
+
+// Carries the observed usage over onto the Items once a patch has been applied. Expects the
+// Stack to be locked.
+func (s *Stack) keepUsage(db *database.Database, lastUsed map[string]int64) {
+	for id, item := range db.Items {
+		db.Items[id] = s.withUsage(id, item, lastUsed)
+	}
+
+	for id, trashed := range db.Trash.Items {
+		trashed.Item = s.withUsage(id, trashed.Item, lastUsed)
+		db.Trash.Items[id] = trashed
+	}
+}
+
+func (s *Stack) withUsage(id string, item database.Item, lastUsed map[string]int64) database.Item {
+	item.LastUsed = max(item.LastUsed, lastUsed[id])
+
+	if params, ok := hotp(item); ok && params.Counter < s.counters[params.Secret] {
+		params.Counter = s.counters[params.Secret]
+		item.TwoFactorSecret = params.URI()
+	}
+
+	return item
+}
+
+// Parses the Item's two factor secret if it's an HOTP one
+func hotp(item database.Item) (twofactor.Params, bool) {
+	if len(item.TwoFactorSecret) == 0 {
+		return twofactor.Params{}, false
+	}
+
+	params, err := twofactor.Parse(item.TwoFactorSecret)
+	if err != nil || params.Kind != twofactor.HOTP_KIND {
+		return twofactor.Params{}, false
+	}
+
+	return params, true
+}
@@ -1,11 +1,10 @@
 package key
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"sync"
 	"time"
-
-	"github.com/zalando/go-keyring"
 )
 
 var (
@@ -19,54 +18,114 @@ type Data struct {
 	Created int64  `json:"created"`
 }
 
-func toJSON(data Data) (string, error) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return "", err
+// The session keys of every unlocked vault, by storage id. They're held in locked memory for
+// as long as each session lasts and never written anywhere, unless a session is remembered
+// (see remember.go).
+var sessions struct {
+	sync.Mutex
+	data map[string]*Data
+}
+
+// Holds a copy of the vault's key in memory for its session, replacing any previous one
+func Set(id, key []byte) error {
+	locked := lock(len(key))
+	copy(locked, key)
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	forget(id)
+
+	if sessions.data == nil {
+		sessions.data = map[string]*Data{}
 	}
-	return string(jsonData), nil
+
+	sessions.data[string(id)] = &Data{
+		Id:      bytes.Clone(id),
+		Key:     locked,
+		Created: time.Now().UnixMilli(),
+	}
+
+	return nil
 }
 
-func fromJSON(jsonStr string) (Data, error) {
-	var data Data
-	err := json.Unmarshal([]byte(jsonStr), &data)
-	if err != nil {
-		return Data{}, err
+// Gets a copy of the vault's session key, so it stays intact even if the session is deleted
+// while it's in use. The caller should clear its Key once it's done with it.
+func Get(id []byte) (Data, error) {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	data, ok := sessions.data[string(id)]
+	if !ok {
+		return Data{}, ErrKeyNotFound
 	}
-	return data, nil
+
+	return Data{
+		Id:      bytes.Clone(data.Id),
+		Key:     bytes.Clone(data.Key),
+		Created: data.Created,
+	}, nil
 }
 
-func Set(id, key []byte) error {
-	Delete() // remove stale keys
+// Checks if the vault has a session key, without copying it
+func Has(id []byte) bool {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	_, ok := sessions.data[string(id)]
 
-	created := time.Now().UnixMilli()
-	data := Data{
-		Id:      id,
-		Key:     key,
-		Created: created,
+	return ok
+}
+
+// Zeroes and forgets the vault's session key
+func Delete(id []byte) error {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	forget(id)
+
+	return nil
+}
+
+// Zeroes and forgets every session key
+func DeleteAll() {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	for id := range sessions.data {
+		forget([]byte(id))
 	}
-	json, err := toJSON(data)
-	if err != nil {
-		return err
+}
+
+// Expects the sessions to be locked
+func forget(id []byte) {
+	data, ok := sessions.data[string(id)]
+	if !ok {
+		return
 	}
 
-	return keyring.Set("Imcrypt", "key", json)
+	unlock(data.Key)
+	delete(sessions.data, string(id))
 }
 
-func Get() (Data, error) {
-	json, err := keyring.Get("Imcrypt", "key")
-	if err != nil {
-		return Data{}, ErrKeyNotFound
-	}
+// Allocates a buffer that's kept out of swap where possible
+func lock(size int) []byte {
+	buf := make([]byte, size)
 
-	data, err := fromJSON(json)
-	if err != nil {
-		return Data{}, err
+	if size > 0 {
+		mlock(buf) // best effort, it can fail if the memlock limit is reached
 	}
 
-	return data, nil
+	return buf
 }
 
-func Delete() error {
-	return keyring.Delete("Imcrypt", "key")
+// Zeroes a buffer from lock and lets it be swapped again
+func unlock(buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+
+	if len(buf) > 0 {
+		munlock(buf)
+	}
 }
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package key
+
+func mlock(buf []byte) error {
+	return nil
+}
+
+func munlock(buf []byte) error {
+	return nil
+}
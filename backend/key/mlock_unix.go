@@ -0,0 +1,13 @@
+//go:build unix
+
+package key
+
+import "golang.org/x/sys/unix"
+
+func mlock(buf []byte) error {
+	return unix.Mlock(buf)
+}
+
+func munlock(buf []byte) error {
+	return unix.Munlock(buf)
+}
@@ -0,0 +1,15 @@
+package key
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func mlock(buf []byte) error {
+	return windows.VirtualLock(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+}
+
+func munlock(buf []byte) error {
+	return windows.VirtualUnlock(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+}
@@ -0,0 +1,162 @@
+package key
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"imcrypt_v3/backend/crypto"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	service       = "Imcrypt"
+	legacyAccount = "key" // shared by every vault in older versions
+)
+
+// A remembered session key in the keyring, wrapped with the install's secret
+type remembered struct {
+	Id      []byte `json:"id"`
+	Wrapped []byte `json:"wrapped"`
+	Created int64  `json:"created"` // (unix milliseconds)
+	TTL     int64  `json:"ttl"`     // how long it can be recalled for (milliseconds)
+}
+
+// Stores the vault's session key in the keyring so the session can be resumed after a
+// restart, for as long as the session length allows. The key is wrapped with a secret kept in
+// the user's config directory, so the keyring entry alone can't unlock anything.
+func Remember(id []byte, sessionLength time.Duration) error {
+	data, err := Get(id)
+	if err != nil {
+		return err
+	}
+	defer clear(data.Key)
+
+	secret, err := installSecret()
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := crypto.Encrypt(data.Key, secret)
+	if err != nil {
+		return err
+	}
+
+	entry, err := json.Marshal(remembered{
+		Id:      data.Id,
+		Wrapped: wrapped,
+		Created: time.Now().UnixMilli(),
+		TTL:     sessionLength.Milliseconds(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return keyring.Set(service, account(id), string(entry))
+}
+
+// Restores a remembered session key for the given id into memory, returning when it was
+// remembered. Expired or mismatched entries are removed.
+func Recall(id []byte) (time.Time, error) {
+	value, err := keyring.Get(service, account(id))
+	if err != nil {
+		return time.Time{}, ErrKeyNotFound
+	}
+
+	var entry remembered
+
+	err = json.Unmarshal([]byte(value), &entry)
+	if err != nil || entry.Wrapped == nil || !bytes.Equal(entry.Id, id) {
+		Forget(id)
+		return time.Time{}, ErrKeyNotFound
+	}
+
+	created := time.UnixMilli(entry.Created)
+	if time.Since(created) >= time.Duration(entry.TTL)*time.Millisecond {
+		Forget(id)
+		return time.Time{}, ErrKeyExpired
+	}
+
+	secret, err := installSecret()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	key, err := crypto.Decrypt(entry.Wrapped, secret)
+	if err != nil {
+		Forget(id)
+		return time.Time{}, fmt.Errorf("unable to unwrap the remembered key: %v", err)
+	}
+
+	err = Set(entry.Id, key)
+	unlock(key)
+
+	return created, err
+}
+
+// Removes the vault's remembered session key from the keyring
+func Forget(id []byte) error {
+	err := keyring.Delete(service, account(id))
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// Removes the entry older versions shared between every vault, which was either a plaintext
+// key or a remembered one. A remembered key is moved to its vault's own entry.
+func PurgeLegacy() {
+	value, err := keyring.Get(service, legacyAccount)
+	if err != nil {
+		return
+	}
+
+	var entry remembered
+	if json.Unmarshal([]byte(value), &entry) == nil && entry.Wrapped != nil {
+		keyring.Set(service, account(entry.Id), value)
+	}
+
+	keyring.Delete(service, legacyAccount)
+}
+
+// The keyring account a vault's session key is remembered under
+func account(id []byte) string {
+	return "key-" + base64.RawURLEncoding.EncodeToString(id)
+}
+
+// Gets (or creates) the random secret unique to this install
+func installSecret() ([]byte, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "imcrypt", "install.key")
+
+	secret, err := os.ReadFile(path)
+	if err == nil && len(secret) == 32 {
+		return secret, nil
+	}
+
+	secret, err = crypto.GenerateSalt(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(path, secret, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
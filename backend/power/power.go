@@ -0,0 +1,9 @@
+package power
+
+import "context"
+
+// Calls onLock whenever the system is about to suspend or the user's session is locked,
+// until the context is done. Returns an error if the system can't be watched.
+func Watch(ctx context.Context, onLock func()) error {
+	return watch(ctx, onLock)
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package power
+
+import (
+	"context"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	login1      = "org.freedesktop.login1"
+	login1Path  = "/org/freedesktop/login1"
+	managerFace = "org.freedesktop.login1.Manager"
+	sessionFace = "org.freedesktop.login1.Session"
+)
+
+// Listens to logind's PrepareForSleep and Lock signals over the system bus
+func watch(ctx context.Context, onLock func()) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return err
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchInterface(managerFace),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	session := ownSession(conn)
+
+	lockMatch := []dbus.MatchOption{
+		dbus.WithMatchInterface(sessionFace),
+		dbus.WithMatchMember("Lock"),
+	}
+	if session.IsValid() {
+		lockMatch = append(lockMatch, dbus.WithMatchObjectPath(session))
+	}
+
+	err = conn.AddMatchSignal(lockMatch...)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	go func() {
+		defer conn.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+
+				switch signal.Name {
+				case managerFace + ".PrepareForSleep":
+					// Sent with true before suspending and false after resuming
+					if len(signal.Body) > 0 && signal.Body[0] == true {
+						onLock()
+					}
+				case sessionFace + ".Lock":
+					onLock()
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Finds the logind session the app runs in, so other sessions' locks are ignored. Returns an
+// empty path if it can't be found.
+func ownSession(conn *dbus.Conn) dbus.ObjectPath {
+	manager := conn.Object(login1, login1Path)
+
+	var session dbus.ObjectPath
+
+	err := manager.Call(managerFace+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&session)
+	if err == nil {
+		return session
+	}
+
+	if id := os.Getenv("XDG_SESSION_ID"); len(id) > 0 {
+		err = manager.Call(managerFace+".GetSession", 0, id).Store(&session)
+		if err == nil {
+			return session
+		}
+	}
+
+	return ""
+}
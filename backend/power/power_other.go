@@ -0,0 +1,12 @@
+//go:build !linux
+
+package power
+
+import (
+	"context"
+	"errors"
+)
+
+func watch(ctx context.Context, onLock func()) error {
+	return errors.New("watching for suspend and screen locks is only supported on linux")
+}
@@ -0,0 +1,135 @@
+package sshkey
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// A key to be served by the Agent
+type AgentKey struct {
+	PrivateKey string
+	Passphrase string
+	Comment    string
+}
+
+// A local ssh-agent serving keys from the vault over a unix socket
+type Agent struct {
+	Path     string // the socket's path, for SSH_AUTH_SOCK
+	dir      string
+	keyring  agent.Agent
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{} // open client connections, closed by Stop
+	stopped bool
+}
+
+// Starts an ssh-agent holding the given keys on a new socket in a private temp directory
+func StartAgent(keys []AgentKey) (*Agent, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("there are no keys to serve")
+	}
+
+	keyring := agent.NewKeyring()
+
+	for _, k := range keys {
+		key, err := ParsePrivateKey(k.PrivateKey, k.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		err = keyring.Add(agent.AddedKey{
+			PrivateKey: key,
+			Comment:    k.Comment,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "imcrypt-agent-")
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "agent.sock")
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	os.Chmod(path, 0600)
+
+	a := &Agent{
+		Path:     path,
+		dir:      dir,
+		keyring:  keyring,
+		listener: listener,
+		conns:    map[net.Conn]struct{}{},
+	}
+
+	a.wg.Add(1)
+	go a.serve()
+
+	return a, nil
+}
+
+// Stops the agent, disconnecting its clients, forgetting its keys and removing its socket
+func (a *Agent) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	a.listener.Close()
+	for conn := range a.conns {
+		conn.Close()
+	}
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	a.keyring.RemoveAll()
+	os.RemoveAll(a.dir)
+}
+
+func (a *Agent) serve() {
+	defer a.wg.Done()
+
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		a.mu.Lock()
+		if a.stopped {
+			a.mu.Unlock()
+			conn.Close()
+			return
+		}
+		a.conns[conn] = struct{}{}
+		a.wg.Add(1)
+		a.mu.Unlock()
+
+		go func() {
+			defer a.wg.Done()
+			defer a.forget(conn)
+
+			agent.ServeAgent(a.keyring, conn)
+		}()
+	}
+}
+
+// Closes a client connection that's done, and stops tracking it
+func (a *Agent) forget(conn net.Conn) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	conn.Close()
+	delete(a.conns, conn)
+}
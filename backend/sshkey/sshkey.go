@@ -0,0 +1,155 @@
+package sshkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	ED25519_ALGORITHM = "ED25519"
+	RSA_ALGORITHM     = "RSA"
+	ECDSA_ALGORITHM   = "ECDSA"
+)
+
+// Generates a new key pair, returning the OpenSSH formatted private key (encrypted with the
+// passphrase, if there is one) and the authorized_keys formatted public key. Bits is ignored
+// for Ed25519, and defaults to 4096 for RSA and 256 for ECDSA (256, 384 or 521).
+func Generate(algorithm string, bits int, comment, passphrase string) (string, string, error) {
+	var key crypto.Signer
+	var err error
+
+	switch strings.ToUpper(strings.TrimSpace(algorithm)) {
+	case ED25519_ALGORITHM:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case RSA_ALGORITHM:
+		if bits == 0 {
+			bits = 4096
+		}
+
+		if bits < 2048 {
+			return "", "", fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+
+		key, err = rsa.GenerateKey(rand.Reader, bits)
+	case ECDSA_ALGORITHM:
+		var curve elliptic.Curve
+
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return "", "", fmt.Errorf("ECDSA keys must be 256, 384 or 521 bits")
+		}
+
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return "", "", fmt.Errorf("the algorithm must be %s, %s, or %s", ED25519_ALGORITHM, RSA_ALGORITHM, ECDSA_ALGORITHM)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var block *pem.Block
+
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, comment)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	publicKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(pem.EncodeToMemory(block)), FormatPublicKey(publicKey, comment), nil
+}
+
+// Parses a private key, decrypting it with the passphrase if it's encrypted
+func ParsePrivateKey(privateKey, passphrase string) (any, error) {
+	var key any
+	var err error
+
+	if len(passphrase) > 0 {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+	} else {
+		key, err = ssh.ParseRawPrivateKey([]byte(privateKey))
+	}
+
+	if _, missing := err.(*ssh.PassphraseMissingError); missing {
+		return nil, fmt.Errorf("the private key is encrypted, but no passphrase was given")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the private key: %v", err)
+	}
+
+	return key, nil
+}
+
+// Derives the authorized_keys formatted public key from a private key
+func PublicKeyOf(privateKey, passphrase, comment string) (string, error) {
+	key, err := ParsePrivateKey(privateKey, passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatPublicKey(signer.PublicKey(), comment), nil
+}
+
+// Gets the SHA256 fingerprint of an authorized_keys formatted public key
+func Fingerprint(publicKey string) (string, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the public key: %v", err)
+	}
+
+	return ssh.FingerprintSHA256(key), nil
+}
+
+// Checks if two authorized_keys formatted public keys are the same key, ignoring comments
+func SamePublicKey(a, b string) bool {
+	ka, _, _, _, err := ssh.ParseAuthorizedKey([]byte(a))
+	if err != nil {
+		return false
+	}
+
+	kb, _, _, _, err := ssh.ParseAuthorizedKey([]byte(b))
+	if err != nil {
+		return false
+	}
+
+	return ssh.FingerprintSHA256(ka) == ssh.FingerprintSHA256(kb)
+}
+
+// Formats a public key as an authorized_keys line
+func FormatPublicKey(key ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	if comment = strings.TrimSpace(comment); len(comment) > 0 {
+		line += " " + comment
+	}
+
+	return line
+}
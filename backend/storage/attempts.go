@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"time"
+)
+
+// Failed unlock attempts allowed before backing off
+const freeAttempts = 3
+
+// The longest a lockout can last
+const maxLockout = time.Hour
+
+// How long until another unlock attempt is allowed, 0 if it's allowed now
+func (s *Storage) LockoutRemaining(now time.Time) time.Duration {
+	if s.LockedUntil == 0 {
+		return 0
+	}
+
+	remaining := time.UnixMilli(s.LockedUntil).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// Records a failed unlock attempt, returning how long the next attempt is locked out for.
+// The lockout doubles with every failure past the free ones, up to an hour.
+func (s *Storage) RecordFailedAttempt(now time.Time) time.Duration {
+	s.FailedAttempts++
+
+	if s.FailedAttempts < freeAttempts {
+		return 0
+	}
+
+	lockout := maxLockout
+	if exponent := s.FailedAttempts - freeAttempts; exponent < 12 {
+		lockout = min(time.Second<<exponent, maxLockout)
+	}
+
+	s.LockedUntil = now.Add(lockout).UnixMilli()
+
+	return lockout
+}
+
+// Checks if enough attempts have failed in a row to wipe the key, per WipeAfter
+func (s *Storage) ShouldWipe() bool {
+	return s.WipeAfter > 0 && s.FailedAttempts >= s.WipeAfter
+}
+
+// Forgets the failed attempts, after a successful unlock. Returns whether there were any.
+func (s *Storage) ResetFailedAttempts() bool {
+	if s.FailedAttempts == 0 && s.LockedUntil == 0 {
+		return false
+	}
+
+	s.FailedAttempts = 0
+	s.LockedUntil = 0
+
+	return true
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Generates a new 24-word (BIP39) recovery phrase for the database key in the keyring,
+// replacing the previous one's slot
+func (s *Storage) CreateRecoveryPhrase() (string, error) {
+	databaseKey, err := s.encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	defer clear(databaseKey)
+
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+
+	phrase, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", err
+	}
+
+	slots := s.KeySlots
+	s.KeySlots = slices.DeleteFunc(slices.Clone(slots), func(slot KeySlot) bool {
+		return slot.Type == RECOVERY_SLOT
+	})
+
+	_, err = s.addKeySlot(databaseKey, RECOVERY_SLOT, "Recovery phrase", entropy)
+	if err != nil {
+		s.KeySlots = slots
+		return "", err
+	}
+
+	return phrase, nil
+}
+
+// Checks if the Storage has a recovery phrase slot
+func (s *Storage) HasRecoveryPhrase() bool {
+	return slices.ContainsFunc(s.KeySlots, func(slot KeySlot) bool {
+		return slot.Type == RECOVERY_SLOT
+	})
+}
+
+// Lowercases the phrase and collapses its whitespace
+func normalizePhrase(phrase string) string {
+	return strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+}
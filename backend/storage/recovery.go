@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"imcrypt_v3/backend/crypto"
+	"strings"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// How many recovery codes are generated at a time
+const RecoveryCodeCount = 10
+
+// Lowercase letters and digits, without the easily confused 0/o and 1/l
+const recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+// A hashed single-use recovery code
+type RecoveryCode struct {
+	Hash []byte
+	Salt []byte
+	Used bool
+}
+
+// Replaces the recovery codes with new ones, returning them. They're formatted as
+// xxxxx-xxxxx and only their hashes are kept.
+func (s *Storage) GenerateRecoveryCodes() ([]string, error) {
+	codes := []string{}
+	hashed := []RecoveryCode{}
+
+	for range RecoveryCodeCount {
+		code, err := gonanoid.Generate(recoveryCodeAlphabet, 10)
+		if err != nil {
+			return nil, err
+		}
+
+		code = code[:5] + "-" + code[5:]
+
+		salt, err := crypto.GenerateSalt(16)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := crypto.Hash([]byte(code), salt)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashed = append(hashed, RecoveryCode{Hash: hash, Salt: salt})
+	}
+
+	s.TwoFactorRecoveryCodes = hashed
+	s.TwoFactorRecoveryHash = nil
+	s.TwoFactorRecoverySalt = nil
+
+	return codes, nil
+}
+
+// Checks the code against the unused recovery codes, marking the matching one as used
+func (s *Storage) UseRecoveryCode(code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	lowered := strings.ToLower(code)
+
+	for i, recovery := range s.TwoFactorRecoveryCodes {
+		if recovery.Used {
+			continue
+		}
+
+		hash, err := crypto.Hash([]byte(lowered), recovery.Salt)
+		if err != nil {
+			return false, err
+		}
+
+		if hmac.Equal(hash, recovery.Hash) {
+			s.TwoFactorRecoveryCodes[i].Used = true
+			return true, nil
+		}
+	}
+
+	// Storages from before multiple recovery codes have a single (case-sensitive) one
+	if s.TwoFactorRecoveryHash != nil {
+		hash, err := crypto.Hash([]byte(code), s.TwoFactorRecoverySalt)
+		if err != nil {
+			return false, err
+		}
+
+		if hmac.Equal(hash, s.TwoFactorRecoveryHash) {
+			s.TwoFactorRecoveryHash = nil
+			s.TwoFactorRecoverySalt = nil
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Counts the recovery codes that haven't been used yet
+func (s *Storage) RemainingRecoveryCodes() int {
+	remaining := 0
+
+	for _, recovery := range s.TwoFactorRecoveryCodes {
+		if !recovery.Used {
+			remaining++
+		}
+	}
+
+	if s.TwoFactorRecoveryHash != nil {
+		remaining++
+	}
+
+	return remaining
+}
+
+// Forgets every recovery code
+func (s *Storage) ClearRecoveryCodes() {
+	s.TwoFactorRecoveryCodes = nil
+	s.TwoFactorRecoveryHash = nil
+	s.TwoFactorRecoverySalt = nil
+}
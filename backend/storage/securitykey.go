@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"imcrypt_v3/backend/crypto"
+	"io"
+	"slices"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+var ErrSecurityKeyRequired = errors.New("this image requires its security key alongside the master password")
+
+// A second factor answering a challenge (the salt) with an HMAC of it, under a secret it never
+// reveals. That's what a FIDO2 authenticator's hmac-secret extension does for a credential.
+type Authenticator interface {
+	HMACSecret(credentialId, salt []byte) ([]byte, error)
+}
+
+// Requires the security key alongside the master password, from then on. The database key is
+// split into two random shares: the password slot wraps one and a SECURITY_KEY slot, whose
+// wrapping key comes from the authenticator's response, wraps the other. Neither unwraps the
+// database key on its own. The other slots are separate ways in and still work on their own.
+func (s *Storage) AddSecurityKey(password, keyFilePath string, auth Authenticator, credentialId []byte, label string) (string, error) {
+	if s.securityKeySlot() != nil {
+		return "", fmt.Errorf("a security key is already required, remove it first")
+	}
+
+	passwordHash, err := s.HashPassword(password, keyFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	databaseKey, err := s.unlock(PASSWORD_SLOT, passwordHash, nil)
+	if err != nil {
+		return "", fmt.Errorf("the current password is incorrect")
+	}
+	defer clear(databaseKey)
+
+	share, err := crypto.GenerateSalt(32)
+	if err != nil {
+		return "", err
+	}
+	defer clear(share)
+
+	id, slot, err := newKeySlot(SECURITY_KEY_SLOT, label)
+	if err != nil {
+		return "", err
+	}
+
+	slot.CredentialId = credentialId
+
+	// hmac-secret takes a 32 byte salt
+	slot.Salt, err = crypto.GenerateSalt(32)
+	if err != nil {
+		return "", err
+	}
+
+	wrappingKey, err := securityKeyWrappingKey(auth, slot)
+	if err != nil {
+		return "", err
+	}
+
+	slot.WrappedKey, err = crypto.Encrypt(share, wrappingKey)
+	if err != nil {
+		return "", err
+	}
+
+	passwordShare := xorKeys(databaseKey, share)
+	defer clear(passwordShare)
+
+	return id, s.replacePasswordSlot(passwordShare, passwordHash, &slot)
+}
+
+// Stops requiring the security key, which has to answer one last time, so the master password
+// unwraps the whole database key again
+func (s *Storage) RemoveSecurityKey(password, keyFilePath string, auth Authenticator) error {
+	if s.securityKeySlot() == nil {
+		return fmt.Errorf("this image doesn't require a security key")
+	}
+
+	passwordHash, err := s.HashPassword(password, keyFilePath)
+	if err != nil {
+		return err
+	}
+
+	databaseKey, err := s.unlockPassword(passwordHash, auth)
+	if errors.Is(err, ErrNoMatchingSlot) {
+		return fmt.Errorf("the current password is incorrect")
+	}
+	if err != nil {
+		return err
+	}
+	defer clear(databaseKey)
+
+	return s.replacePasswordSlot(databaseKey, passwordHash, nil)
+}
+
+// Forgets the security key without it having to answer, for when the master password is reset
+// after unlocking with the recovery phrase, which is also the way back in when the security key
+// is lost. Until SetPassword replaces the password slot, it only wraps its share of the key.
+func (s *Storage) ForgetSecurityKey() {
+	s.KeySlots = slices.DeleteFunc(slices.Clone(s.KeySlots), func(slot KeySlot) bool {
+		return slot.Type == SECURITY_KEY_SLOT
+	})
+}
+
+// Checks if the master password has to be given alongside a security key
+func (s *Storage) RequiresSecurityKey() bool {
+	return s.securityKeySlot() != nil
+}
+
+// Unlocks the database key with the password hash, and the security key's share of it if one
+// is required
+func (s *Storage) unlockPassword(passwordHash []byte, auth Authenticator) ([]byte, error) {
+	if !s.RequiresSecurityKey() {
+		return s.unlock(PASSWORD_SLOT, passwordHash, nil)
+	}
+
+	share, err := s.securityKeyShare(auth)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(share)
+
+	return s.unlock(PASSWORD_SLOT, passwordHash, share)
+}
+
+// Asks the authenticator for the SECURITY_KEY slot's wrapping key, and unwraps its share
+func (s *Storage) securityKeyShare(auth Authenticator) ([]byte, error) {
+	slot := s.securityKeySlot()
+
+	if auth == nil {
+		return nil, ErrSecurityKeyRequired
+	}
+
+	wrappingKey, err := securityKeyWrappingKey(auth, *slot)
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := crypto.Decrypt(slot.WrappedKey, wrappingKey)
+	if err != nil {
+		return nil, fmt.Errorf("that security key isn't the one this image requires")
+	}
+
+	return share, nil
+}
+
+// Replaces the password slot with one wrapping the given key (the database key, or the
+// password's share of it). The security key's slot replaces the old one, nil to remove it.
+func (s *Storage) replacePasswordSlot(key, passwordHash []byte, securityKey *KeySlot) error {
+	slots := s.KeySlots
+	s.KeySlots = slices.DeleteFunc(slices.Clone(slots), func(slot KeySlot) bool {
+		return slot.Type == PASSWORD_SLOT || slot.Type == SECURITY_KEY_SLOT
+	})
+
+	_, err := s.addKeySlot(key, PASSWORD_SLOT, "Master password", passwordHash)
+	if err != nil {
+		s.KeySlots = slots
+		return err
+	}
+
+	if securityKey != nil {
+		s.KeySlots = append(s.KeySlots, *securityKey)
+	}
+
+	return nil
+}
+
+func (s *Storage) securityKeySlot() *KeySlot {
+	index := slices.IndexFunc(s.KeySlots, func(slot KeySlot) bool {
+		return slot.Type == SECURITY_KEY_SLOT
+	})
+	if index == -1 {
+		return nil
+	}
+
+	return &s.KeySlots[index]
+}
+
+// Derives a SECURITY_KEY slot's wrapping key from the authenticator's response to its salt
+func securityKeyWrappingKey(auth Authenticator, slot KeySlot) ([]byte, error) {
+	response, err := auth.HMACSecret(slot.CredentialId, slot.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("the security key didn't answer: %v", err)
+	}
+	defer clear(response)
+
+	if len(response) < 32 {
+		return nil, fmt.Errorf("the security key's answer is too short")
+	}
+
+	wrappingKey := make([]byte, 32)
+
+	_, err = io.ReadFull(hkdf.New(sha256.New, response, slot.Salt, slot.CredentialId), wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrappingKey, nil
+}
+
+func xorKeys(a, b []byte) []byte {
+	xored := make([]byte, len(a))
+
+	for i := range a {
+		xored[i] = a[i] ^ b[i]
+	}
+
+	return xored
+}
@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"imcrypt_v3/backend/crypto"
+	"imcrypt_v3/backend/database"
+	"imcrypt_v3/backend/key"
+	"imcrypt_v3/backend/utils"
+	"testing"
+)
+
+// Emulates a FIDO2 authenticator's hmac-secret extension. Each credential has a random secret
+// (CredRandom) that never leaves the authenticator, and its output for a salt is
+// HMAC-SHA256(CredRandom, salt).
+type emulatedAuthenticator struct {
+	credentials map[string][]byte
+}
+
+func newEmulatedAuthenticator() *emulatedAuthenticator {
+	return &emulatedAuthenticator{credentials: map[string][]byte{}}
+}
+
+func (e *emulatedAuthenticator) makeCredential(t *testing.T) []byte {
+	t.Helper()
+
+	credentialId := make([]byte, 64)
+	credRandom := make([]byte, 32)
+
+	if _, err := rand.Read(credentialId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(credRandom); err != nil {
+		t.Fatal(err)
+	}
+
+	e.credentials[string(credentialId)] = credRandom
+
+	return credentialId
+}
+
+func (e *emulatedAuthenticator) HMACSecret(credentialId, salt []byte) ([]byte, error) {
+	if len(salt) != 32 {
+		return nil, fmt.Errorf("hmac-secret salts must be 32 bytes, got %d", len(salt))
+	}
+
+	credRandom, ok := e.credentials[string(credentialId)]
+	if !ok {
+		return nil, errors.New("no credentials")
+	}
+
+	mac := hmac.New(sha256.New, credRandom)
+	mac.Write(salt)
+
+	return mac.Sum(nil), nil
+}
+
+// Makes a Storage with a password slot and an empty database, as InitializeStorage does,
+// returning it with its database key
+func newTestStorage(t *testing.T, password string) (*Storage, []byte) {
+	t.Helper()
+
+	passwordSalt, err := crypto.GenerateSalt(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	databaseKey, err := GenerateDatabaseKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Storage{Id: []byte(t.Name()), PasswordSalt: passwordSalt}
+
+	err = key.Set(s.Id, databaseKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { key.Delete(s.Id) })
+
+	err = s.SetPassword(password, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := database.NewDatabase()
+
+	err = s.SetDatabase(&db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, databaseKey
+}
+
+// Round trips the Storage through gob, like writing it onto an image and reading it back
+func reread(t *testing.T, s *Storage) *Storage {
+	t.Helper()
+
+	gobbed, err := utils.Gobify(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read Storage
+
+	err = utils.Degob(gobbed, &read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &read
+}
+
+func TestSecurityKeyIsRequiredAlongsidePassword(t *testing.T) {
+	s, databaseKey := newTestStorage(t, "hunter2")
+
+	auth := newEmulatedAuthenticator()
+
+	_, err := s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "YubiKey")
+	if err != nil {
+		t.Fatalf("AddSecurityKey() failed: %v", err)
+	}
+
+	s = reread(t, s)
+
+	if !s.RequiresSecurityKey() {
+		t.Fatal("RequiresSecurityKey() = false after adding one")
+	}
+
+	_, err = s.UnlockWithPassword("hunter2", "", nil)
+	if !errors.Is(err, ErrSecurityKeyRequired) {
+		t.Errorf("UnlockWithPassword() without the security key = %v, want ErrSecurityKeyRequired", err)
+	}
+
+	// Even knowing the password, its slot only unwraps a share that isn't the database key
+	passwordHash, err := s.HashPassword("hunter2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.unlock(PASSWORD_SLOT, passwordHash, nil)
+	if !errors.Is(err, ErrNoMatchingSlot) {
+		t.Errorf("unlocking the password slot alone = %v, want ErrNoMatchingSlot", err)
+	}
+
+	for _, slot := range s.KeySlots {
+		if slot.Type != PASSWORD_SLOT {
+			continue
+		}
+
+		wrappingKey, err := crypto.Hash(passwordHash, slot.Salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := crypto.Decrypt(slot.WrappedKey, wrappingKey)
+		if err != nil {
+			t.Fatalf("the password no longer opens its own slot: %v", err)
+		}
+
+		if bytes.Equal(share, databaseKey) || s.VerifyKey(share) == nil {
+			t.Error("the password slot still wraps the database key itself")
+		}
+	}
+
+	// Another authenticator, even with the same credential id, doesn't answer the same way
+	other := newEmulatedAuthenticator()
+	for credentialId := range auth.credentials {
+		other.credentials[credentialId] = make([]byte, 32)
+	}
+
+	_, err = s.UnlockWithPassword("hunter2", "", other)
+	if err == nil {
+		t.Error("UnlockWithPassword() with the wrong security key succeeded")
+	}
+
+	_, err = s.UnlockWithPassword("hunter3", "", auth)
+	if !errors.Is(err, ErrNoMatchingSlot) {
+		t.Errorf("UnlockWithPassword() with the wrong password = %v, want ErrNoMatchingSlot", err)
+	}
+
+	unlocked, err := s.UnlockWithPassword("hunter2", "", auth)
+	if err != nil {
+		t.Fatalf("UnlockWithPassword() with both factors failed: %v", err)
+	}
+
+	if !bytes.Equal(unlocked, databaseKey) {
+		t.Error("UnlockWithPassword() with both factors didn't return the database key")
+	}
+
+	err = s.VerifyPassword("hunter2", "", nil)
+	if !errors.Is(err, ErrSecurityKeyRequired) {
+		t.Errorf("VerifyPassword() without the security key = %v, want ErrSecurityKeyRequired", err)
+	}
+
+	err = s.VerifyPassword("hunter2", "", auth)
+	if err != nil {
+		t.Errorf("VerifyPassword() with both factors failed: %v", err)
+	}
+}
+
+func TestSecurityKeyChecksPassword(t *testing.T) {
+	s, _ := newTestStorage(t, "hunter2")
+
+	auth := newEmulatedAuthenticator()
+
+	_, err := s.AddSecurityKey("hunter3", "", auth, auth.makeCredential(t), "")
+	if err == nil {
+		t.Fatal("AddSecurityKey() with the wrong password succeeded")
+	}
+
+	if s.RequiresSecurityKey() {
+		t.Error("a failed AddSecurityKey() left a security key behind")
+	}
+
+	_, err = s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err == nil {
+		t.Error("a second AddSecurityKey() succeeded")
+	}
+}
+
+func TestRemoveSecurityKey(t *testing.T) {
+	s, databaseKey := newTestStorage(t, "hunter2")
+
+	auth := newEmulatedAuthenticator()
+
+	_, err := s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.RemoveSecurityKey("hunter2", "", nil)
+	if !errors.Is(err, ErrSecurityKeyRequired) {
+		t.Errorf("RemoveSecurityKey() without the security key = %v, want ErrSecurityKeyRequired", err)
+	}
+
+	err = s.RemoveSecurityKey("hunter3", "", auth)
+	if err == nil {
+		t.Error("RemoveSecurityKey() with the wrong password succeeded")
+	}
+
+	err = s.RemoveSecurityKey("hunter2", "", auth)
+	if err != nil {
+		t.Fatalf("RemoveSecurityKey() failed: %v", err)
+	}
+
+	s = reread(t, s)
+
+	if s.RequiresSecurityKey() {
+		t.Error("RequiresSecurityKey() = true after removing it")
+	}
+
+	unlocked, err := s.UnlockWithPassword("hunter2", "", nil)
+	if err != nil {
+		t.Fatalf("UnlockWithPassword() after removing the security key failed: %v", err)
+	}
+
+	if !bytes.Equal(unlocked, databaseKey) {
+		t.Error("UnlockWithPassword() didn't return the database key")
+	}
+}
+
+func TestSetPasswordKeepsSecurityKey(t *testing.T) {
+	s, databaseKey := newTestStorage(t, "hunter2")
+
+	auth := newEmulatedAuthenticator()
+
+	_, err := s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetPassword("correct horse", "", nil)
+	if !errors.Is(err, ErrSecurityKeyRequired) {
+		t.Errorf("SetPassword() without the security key = %v, want ErrSecurityKeyRequired", err)
+	}
+
+	err = s.SetPassword("correct horse", "", auth)
+	if err != nil {
+		t.Fatalf("SetPassword() failed: %v", err)
+	}
+
+	_, err = s.UnlockWithPassword("correct horse", "", nil)
+	if !errors.Is(err, ErrSecurityKeyRequired) {
+		t.Errorf("UnlockWithPassword() without the security key = %v, want ErrSecurityKeyRequired", err)
+	}
+
+	unlocked, err := s.UnlockWithPassword("correct horse", "", auth)
+	if err != nil {
+		t.Fatalf("UnlockWithPassword() with the new password failed: %v", err)
+	}
+
+	if !bytes.Equal(unlocked, databaseKey) {
+		t.Error("UnlockWithPassword() didn't return the database key")
+	}
+}
+
+func TestForgetSecurityKeyOnReset(t *testing.T) {
+	s, databaseKey := newTestStorage(t, "hunter2")
+
+	phrase, err := s.CreateRecoveryPhrase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := newEmulatedAuthenticator()
+
+	_, err = s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The recovery phrase is a separate way in, for when the security key is lost
+	unlocked, err := s.UnlockWithSlot(RECOVERY_SLOT, phrase)
+	if err != nil {
+		t.Fatalf("UnlockWithSlot() with the recovery phrase failed: %v", err)
+	}
+
+	if !bytes.Equal(unlocked, databaseKey) {
+		t.Error("the recovery phrase didn't unlock the database key")
+	}
+
+	s.ForgetSecurityKey()
+
+	err = s.SetPassword("correct horse", "", nil)
+	if err != nil {
+		t.Fatalf("SetPassword() after ForgetSecurityKey() failed: %v", err)
+	}
+
+	unlocked, err = s.UnlockWithPassword("correct horse", "", nil)
+	if err != nil {
+		t.Fatalf("UnlockWithPassword() after the reset failed: %v", err)
+	}
+
+	if !bytes.Equal(unlocked, databaseKey) {
+		t.Error("UnlockWithPassword() didn't return the database key")
+	}
+}
+
+func TestSecurityKeySlotIsManagedSeparately(t *testing.T) {
+	s, _ := newTestStorage(t, "hunter2")
+
+	auth := newEmulatedAuthenticator()
+
+	id, err := s.AddSecurityKey("hunter2", "", auth, auth.makeCredential(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.RemoveKeySlot(id)
+	if err == nil {
+		t.Error("RemoveKeySlot() removed the security key slot")
+	}
+
+	_, err = s.AddKeySlot(SECURITY_KEY_SLOT, "", "")
+	if err == nil {
+		t.Error("AddKeySlot() added a security key slot")
+	}
+}
@@ -0,0 +1,502 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"imcrypt_v3/backend/crypto"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	PASSWORD_SLOT   = "PASSWORD"   // the master password (and key file, if required)
+	RECOVERY_SLOT   = "RECOVERY"   // a generated 24-word recovery phrase
+	KEY_FILE_SLOT   = "KEY_FILE"   // a key file on its own
+	PUBLIC_KEY_SLOT = "PUBLIC_KEY" // a teammate's X25519 public key, unlocked with their private key
+
+	SECURITY_KEY_SLOT = "SECURITY_KEY" // a FIDO2 authenticator's hmac-secret, required alongside the master password
+)
+
+var ErrNoMatchingSlot = errors.New("unable to unlock with the given credentials")
+
+// A copy of the database key, encrypted (wrapped) with a key derived from one unlock method.
+// Any slot can unlock the database, and slots can be added or removed without re-encrypting it.
+type KeySlot struct {
+	Id           string
+	Type         string
+	Label        string
+	Created      int64  // (unix timestamp)
+	Salt         []byte // for deriving the wrapping key
+	PublicKey    []byte // PUBLIC_KEY only, the teammate's public key
+	EphemeralKey []byte // PUBLIC_KEY only, the public half of the key agreed with the teammate's
+	CredentialId []byte // SECURITY_KEY only, the authenticator's credential
+	WrappedKey   []byte
+}
+
+// A key slot's details, without its key material
+type KeySlotInfo struct {
+	Id          string `json:"id"`
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	Created     int64  `json:"created"`
+	Fingerprint string `json:"fingerprint"` // PUBLIC_KEY only, the SHA256 of the public key
+}
+
+// Generates a random database key
+func GenerateDatabaseKey() ([]byte, error) {
+	return crypto.GenerateSalt(32)
+}
+
+// Unlocks the database key with the master password (and key file, if required), along with
+// the security key if one is required, see securitykey.go. Storages from before key slots are
+// upgraded to a random database key behind a password slot.
+func (s *Storage) UnlockWithPassword(password, keyFilePath string, auth Authenticator) ([]byte, error) {
+	passwordHash, err := s.HashPassword(password, keyFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.KeySlots) == 0 {
+		return s.upgradeToKeySlots(passwordHash)
+	}
+
+	return s.unlockPassword(passwordHash, auth)
+}
+
+// Checks the master password (along with the key file and security key, if the Storage requires
+// them) against its slot, without unlocking anything
+func (s *Storage) VerifyPassword(password, keyFilePath string, auth Authenticator) error {
+	passwordHash, err := s.HashPassword(password, keyFilePath)
+	if err != nil {
+		return err
+	}
+
+	databaseKey, err := s.unlockPassword(passwordHash, auth)
+	if errors.Is(err, ErrNoMatchingSlot) {
+		return fmt.Errorf("the current password is incorrect")
+	}
+	if err != nil {
+		return err
+	}
+	clear(databaseKey)
+
+	return nil
+}
+
+// Unlocks the database key with a non-password slot. The secret is the recovery phrase, the
+// key file's path, or the teammate's base64 encoded private key.
+func (s *Storage) UnlockWithSlot(slotType, secret string) ([]byte, error) {
+	if slotType == PASSWORD_SLOT {
+		return nil, fmt.Errorf("use the password to unlock a password slot")
+	}
+
+	material, err := slotSecret(slotType, secret, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.unlock(slotType, material, nil)
+}
+
+// Adds a key file or public key slot wrapping the database key in the keyring, returning its
+// id. The secret is the key file's path, or the teammate's base64 encoded public key.
+func (s *Storage) AddKeySlot(slotType, label, secret string) (string, error) {
+	if slotType == PASSWORD_SLOT {
+		return "", fmt.Errorf("the master password slot can only be changed, not added")
+	}
+
+	if slotType == RECOVERY_SLOT {
+		return "", fmt.Errorf("recovery phrases are generated, not added")
+	}
+
+	if slotType == SECURITY_KEY_SLOT {
+		return "", fmt.Errorf("security keys are added with the master password")
+	}
+
+	databaseKey, err := s.encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	defer clear(databaseKey)
+
+	material, err := slotSecret(slotType, secret, false)
+	if err != nil {
+		return "", err
+	}
+
+	if slotType == PUBLIC_KEY_SLOT && slices.ContainsFunc(s.KeySlots, func(slot KeySlot) bool {
+		return bytes.Equal(slot.PublicKey, material)
+	}) {
+		return "", fmt.Errorf("that public key already has a slot")
+	}
+
+	return s.addKeySlot(databaseKey, slotType, label, material)
+}
+
+// Replaces the master password slot, requiring the key file alongside it if a path is given.
+// If a security key is required, it has to answer, since the slot only wraps the password's
+// share of the database key.
+func (s *Storage) SetPassword(password, keyFilePath string, auth Authenticator) error {
+	if len(password) < 1 {
+		return fmt.Errorf("password must be at least 1 character long")
+	}
+
+	databaseKey, err := s.encryptionKey()
+	if err != nil {
+		return err
+	}
+	defer clear(databaseKey)
+
+	if s.RequiresSecurityKey() {
+		share, err := s.securityKeyShare(auth)
+		if err != nil {
+			return err
+		}
+		defer clear(share)
+
+		databaseKey = xorKeys(databaseKey, share)
+		defer clear(databaseKey)
+	}
+
+	previous := s.RequiresKeyFile
+	s.RequiresKeyFile = len(keyFilePath) > 0
+
+	passwordHash, err := s.HashPassword(password, keyFilePath)
+	if err != nil {
+		s.RequiresKeyFile = previous
+		return err
+	}
+
+	slots := s.KeySlots
+	s.KeySlots = slices.DeleteFunc(slices.Clone(slots), func(slot KeySlot) bool {
+		return slot.Type == PASSWORD_SLOT
+	})
+
+	_, err = s.addKeySlot(databaseKey, PASSWORD_SLOT, "Master password", passwordHash)
+	if err != nil {
+		s.KeySlots = slots
+		s.RequiresKeyFile = previous
+		return err
+	}
+
+	return nil
+}
+
+// Removes a key slot. The master password slot can't be removed.
+func (s *Storage) RemoveKeySlot(id string) error {
+	index := slices.IndexFunc(s.KeySlots, func(slot KeySlot) bool {
+		return slot.Id == id
+	})
+	if index == -1 {
+		return fmt.Errorf("cannot find key slot with id %s", id)
+	}
+
+	if s.KeySlots[index].Type == PASSWORD_SLOT {
+		return fmt.Errorf("the master password slot cannot be removed")
+	}
+
+	if s.KeySlots[index].Type == SECURITY_KEY_SLOT {
+		return fmt.Errorf("the security key can only be removed with the master password")
+	}
+
+	s.KeySlots = slices.Delete(slices.Clone(s.KeySlots), index, index+1)
+
+	return nil
+}
+
+// Lists the key slots' details
+func (s *Storage) ListKeySlots() []KeySlotInfo {
+	infos := []KeySlotInfo{}
+
+	for _, slot := range s.KeySlots {
+		info := KeySlotInfo{
+			Id:      slot.Id,
+			Type:    slot.Type,
+			Label:   slot.Label,
+			Created: slot.Created,
+		}
+
+		if slot.Type == PUBLIC_KEY_SLOT {
+			sum := sha256.Sum256(slot.PublicKey)
+			info.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Generates an X25519 key pair for a teammate, returning the base64 encoded private and
+// public keys. The public key is what gets added as a PUBLIC_KEY slot.
+func GenerateSharingKeyPair() (string, string, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(private.Bytes()), base64.StdEncoding.EncodeToString(private.PublicKey().Bytes()), nil
+}
+
+func (s *Storage) addKeySlot(databaseKey []byte, slotType, label string, material []byte) (string, error) {
+	id, slot, err := newKeySlot(slotType, label)
+	if err != nil {
+		return "", err
+	}
+
+	var wrappingKey []byte
+
+	if slotType == PUBLIC_KEY_SLOT {
+		publicKey, err := ecdh.X25519().NewPublicKey(material)
+		if err != nil {
+			return "", fmt.Errorf("invalid public key: %v", err)
+		}
+
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+
+		shared, err := ephemeral.ECDH(publicKey)
+		if err != nil {
+			return "", err
+		}
+
+		slot.PublicKey = publicKey.Bytes()
+		slot.EphemeralKey = ephemeral.PublicKey().Bytes()
+
+		wrappingKey, err = agreedKey(shared, slot)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		wrappingKey, err = crypto.Hash(material, slot.Salt)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	slot.WrappedKey, err = crypto.Encrypt(databaseKey, wrappingKey)
+	if err != nil {
+		return "", err
+	}
+
+	s.KeySlots = append(slices.Clone(s.KeySlots), slot)
+
+	return id, nil
+}
+
+// Makes an empty key slot with a fresh id and salt
+func newKeySlot(slotType, label string) (string, KeySlot, error) {
+	id, _ := gonanoid.New()
+
+	salt, err := crypto.GenerateSalt(16)
+	if err != nil {
+		return "", KeySlot{}, err
+	}
+
+	return id, KeySlot{
+		Id:      id,
+		Type:    slotType,
+		Label:   strings.TrimSpace(label),
+		Created: time.Now().Unix(),
+		Salt:    salt,
+	}, nil
+}
+
+// Tries every slot of the given type, returning the database key from the first that opens.
+// The share is the security key's share of the database key, when the slot only wraps the
+// other one.
+func (s *Storage) unlock(slotType string, material, share []byte) ([]byte, error) {
+	var private *ecdh.PrivateKey
+
+	if slotType == PUBLIC_KEY_SLOT {
+		var err error
+
+		private, err = ecdh.X25519().NewPrivateKey(material)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %v", err)
+		}
+	}
+
+	for _, slot := range s.KeySlots {
+		if slot.Type != slotType {
+			continue
+		}
+
+		var wrappingKey []byte
+		var err error
+
+		if slotType == PUBLIC_KEY_SLOT {
+			if !bytes.Equal(slot.PublicKey, private.PublicKey().Bytes()) {
+				continue
+			}
+
+			ephemeral, err := ecdh.X25519().NewPublicKey(slot.EphemeralKey)
+			if err != nil {
+				continue
+			}
+
+			shared, err := private.ECDH(ephemeral)
+			if err != nil {
+				continue
+			}
+
+			wrappingKey, err = agreedKey(shared, slot)
+			if err != nil {
+				continue
+			}
+		} else {
+			wrappingKey, err = crypto.Hash(material, slot.Salt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		databaseKey, err := crypto.Decrypt(slot.WrappedKey, wrappingKey)
+		if err != nil {
+			continue
+		}
+
+		if share != nil {
+			unwrapped := databaseKey
+			databaseKey = xorKeys(unwrapped, share)
+			clear(unwrapped)
+		}
+
+		if s.VerifyKey(databaseKey) == nil {
+			return databaseKey, nil
+		}
+	}
+
+	return nil, ErrNoMatchingSlot
+}
+
+// Moves an older storage, whose database key is derived from the password, onto a random
+// database key behind a password slot. Everything encrypted with the old key is re-encrypted,
+// so the password alone can no longer derive the database key once it's changed.
+func (s *Storage) upgradeToKeySlots(passwordHash []byte) ([]byte, error) {
+	oldKey, err := crypto.Hash(passwordHash, s.EncryptionSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := s.getDatabaseWith(oldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	newKey, err := GenerateDatabaseKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy, so a failure part way through leaves the storage untouched
+	upgraded := *s
+	upgraded.Attachments = make(map[string]SealedAttachment, len(s.Attachments))
+
+	err = upgraded.setDatabaseWith(database, newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, attachment := range s.Attachments {
+		attachmentKey, err := crypto.Decrypt(attachment.WrappedKey, oldKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unwrap the attachment's key: %v", err)
+		}
+
+		attachment.WrappedKey, err = crypto.Encrypt(attachmentKey, newKey)
+		if err != nil {
+			return nil, err
+		}
+
+		upgraded.Attachments[id] = attachment
+	}
+
+	if s.EncryptedTwoFactorSecret != nil {
+		secret, err := s.decryptTwoFactorSecret(oldKey)
+		if err != nil {
+			return nil, err
+		}
+
+		upgraded.EncryptedTwoFactorSecret, err = crypto.Encrypt([]byte(secret), newKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	upgraded.KeySlots = nil
+	upgraded.EncryptionSalt = nil
+
+	_, err = upgraded.addKeySlot(newKey, PASSWORD_SLOT, "Master password", passwordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	*s = upgraded
+
+	return newKey, nil
+}
+
+// Turns a slot's secret into the material its wrapping key is derived from. For PUBLIC_KEY
+// slots, it's the public key when adding and the private key when unlocking.
+func slotSecret(slotType, secret string, unlocking bool) ([]byte, error) {
+	switch slotType {
+	case RECOVERY_SLOT:
+		entropy, err := bip39.EntropyFromMnemonic(normalizePhrase(secret))
+		if err != nil {
+			return nil, fmt.Errorf("the recovery phrase is invalid, check it for typos")
+		}
+
+		return entropy, nil
+	case KEY_FILE_SLOT:
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("the key file path cannot be empty")
+		}
+
+		hash, err := crypto.HashFile(secret)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the key file: %v", err)
+		}
+
+		return hash, nil
+	case PUBLIC_KEY_SLOT:
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+		if err != nil || len(decoded) != 32 {
+			if unlocking {
+				return nil, fmt.Errorf("the private key must be a base64 encoded X25519 key")
+			}
+
+			return nil, fmt.Errorf("the public key must be a base64 encoded X25519 key")
+		}
+
+		return decoded, nil
+	}
+
+	return nil, fmt.Errorf("the slot type must be %s, %s, or %s", RECOVERY_SLOT, KEY_FILE_SLOT, PUBLIC_KEY_SLOT)
+}
+
+// Derives a PUBLIC_KEY slot's wrapping key from the agreed secret
+func agreedKey(shared []byte, slot KeySlot) ([]byte, error) {
+	info := append(slices.Clone(slot.EphemeralKey), slot.PublicKey...)
+	wrappingKey := make([]byte, 32)
+
+	_, err := io.ReadFull(hkdf.New(sha256.New, shared, slot.Salt, info), wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrappingKey, nil
+}
@@ -1,44 +1,79 @@
 package storage
 
 import (
-	"bytes"
 	"fmt"
 	"imcrypt_v3/backend/crypto"
 	"imcrypt_v3/backend/database"
 	"imcrypt_v3/backend/key"
 	"imcrypt_v3/backend/utils"
+	"slices"
 )
 
 type Storage struct {
-	Id                    []byte
-	EncryptionSalt        []byte
-	PasswordSalt          []byte
-	EncryptedDatabase     []byte
-	HMAC                  []byte
-	TwoFactorSecret       []byte
-	TwoFactorRecoveryHash []byte
-	TwoFactorRecoverySalt []byte
-	TwoFactorConfirmed    []byte
+	Id                       []byte
+	EncryptionSalt           []byte // derives the database key of older storages, without key slots
+	PasswordSalt             []byte
+	EncryptedDatabase        []byte
+	HMAC                     []byte
+	TwoFactorSecret          []byte // the plaintext TFA secret of older storages
+	EncryptedTwoFactorSecret []byte
+	TwoFactorRecoveryHash    []byte // the single recovery code of older storages
+	TwoFactorRecoverySalt    []byte
+	TwoFactorConfirmed       []byte
+	TwoFactorRecoveryCodes   []RecoveryCode
+	KeySlots                 []KeySlot                   // each wraps the database key, see slots.go
+	Attachments              map[string]SealedAttachment // keyed by the attachment id in the Database
+	RequiresKeyFile          bool                        // a key file's hash is mixed into the password hash
+	FailedAttempts           int                         // failed unlock attempts in a row
+	LockedUntil              int64                       // no unlock attempts until then (unix milliseconds)
+	WipeAfter                int                         // wipe the key after this many failed attempts, 0 to never
+	TwoFactorLastStep        int64                       // the TOTP time step last used, so codes can't be replayed
+}
+
+// An attachment's contents, encrypted with its own key. The key is itself encrypted
+// (wrapped) with the database's encryption key.
+type SealedAttachment struct {
+	WrappedKey []byte
+	Data       []byte
 }
 
 const signature = "imcrypt_v3"
 
 func (s *Storage) SetDatabase(database *database.Database) error {
-	keyData, err := key.Get()
+	encryptionKey, err := s.encryptionKey()
 	if err != nil {
 		return err
 	}
+	defer clear(encryptionKey)
 
-	if !bytes.Equal(s.Id, keyData.Id) {
-		key.Delete()
-		return fmt.Errorf("storage id does not match key id")
+	return s.setDatabaseWith(database, encryptionKey)
+}
+
+func (s *Storage) GetDatabase() (*database.Database, error) {
+	encryptionKey, err := s.encryptionKey()
+	if err != nil {
+		return nil, err
 	}
+	defer clear(encryptionKey)
 
-	encryptionKey, err := crypto.Hash(keyData.Key, s.EncryptionSalt)
+	return s.getDatabaseWith(encryptionKey)
+}
+
+// Checks that the database key decrypts the database, without needing the keyring
+func (s *Storage) VerifyKey(databaseKey []byte) error {
+	if !crypto.ValidateHMAC(s.HMAC, crypto.GenerateHMAC(s.EncryptedDatabase, databaseKey)) {
+		return fmt.Errorf("unable to validate database integrity with given key")
+	}
+
+	_, err := crypto.Decrypt(s.EncryptedDatabase, databaseKey)
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to decrypt database with given key: %v", err)
 	}
 
+	return nil
+}
+
+func (s *Storage) setDatabaseWith(database *database.Database, databaseKey []byte) error {
 	gobbed, err := utils.Gobify(database)
 	if err != nil {
 		return err
@@ -46,51 +81,156 @@ func (s *Storage) SetDatabase(database *database.Database) error {
 
 	signed := utils.Sign(gobbed, []byte(signature))
 
-	encrypted, err := crypto.Encrypt(signed, encryptionKey)
+	encrypted, err := crypto.Encrypt(signed, databaseKey)
 	if err != nil {
 		return err
 	}
 
 	s.EncryptedDatabase = encrypted
-	s.HMAC = crypto.GenerateHMAC(encrypted, encryptionKey)
+	s.HMAC = crypto.GenerateHMAC(encrypted, databaseKey)
 
 	return nil
 }
 
-func (s *Storage) GetDatabase() (*database.Database, error) {
-	keyData, err := key.Get()
+func (s *Storage) getDatabaseWith(databaseKey []byte) (*database.Database, error) {
+	decrypted, err := crypto.Decrypt(s.EncryptedDatabase, databaseKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt database with given key: %v", err)
+	}
+
+	hmac := crypto.GenerateHMAC(s.EncryptedDatabase, databaseKey)
+	if !crypto.ValidateHMAC(s.HMAC, hmac) {
+		return nil, fmt.Errorf("unable to validate database integrity with given key")
+	}
+
+	unsigned := utils.Unsign(decrypted, []byte(signature))
+
+	var database database.Database
+
+	err = utils.Degob(unsigned, &database)
 	if err != nil {
 		return nil, err
 	}
 
-	if !bytes.Equal(s.Id, keyData.Id) {
-		key.Delete()
-		return nil, fmt.Errorf("storage id does not match key id")
+	return &database, nil
+}
+
+// Encrypts and stores an attachment's contents under the given id
+func (s *Storage) SetAttachment(id string, data []byte) error {
+	encryptionKey, err := s.encryptionKey()
+	if err != nil {
+		return err
+	}
+	defer clear(encryptionKey)
+
+	attachmentKey, err := crypto.GenerateSalt(32)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := crypto.Encrypt(data, attachmentKey)
+	if err != nil {
+		return err
 	}
 
-	encryptionKey, err := crypto.Hash(keyData.Key, s.EncryptionSalt)
+	wrappedKey, err := crypto.Encrypt(attachmentKey, encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	if s.Attachments == nil {
+		s.Attachments = make(map[string]SealedAttachment)
+	}
+
+	s.Attachments[id] = SealedAttachment{
+		WrappedKey: wrappedKey,
+		Data:       sealed,
+	}
+
+	return nil
+}
+
+// Retrieves and decrypts an attachment's contents
+func (s *Storage) GetAttachment(id string) ([]byte, error) {
+	attachment, exists := s.Attachments[id]
+	if !exists {
+		return nil, fmt.Errorf("cannot find attachment with id %s", id)
+	}
+
+	encryptionKey, err := s.encryptionKey()
 	if err != nil {
 		return nil, err
 	}
+	defer clear(encryptionKey)
 
-	decrypted, err := crypto.Decrypt(s.EncryptedDatabase, encryptionKey)
+	attachmentKey, err := crypto.Decrypt(attachment.WrappedKey, encryptionKey)
 	if err != nil {
-		return nil, fmt.Errorf("unable to decrypt database with given password hash: %v", err)
+		return nil, fmt.Errorf("unable to unwrap the attachment's key: %v", err)
 	}
 
-	hmac := crypto.GenerateHMAC(s.EncryptedDatabase, encryptionKey)
-	if !crypto.ValidateHMAC(s.HMAC, hmac) {
-		return nil, fmt.Errorf("unable to validate database integrity with given password hash")
+	data, err := crypto.Decrypt(attachment.Data, attachmentKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the attachment: %v", err)
 	}
 
-	unsigned := utils.Unsign(decrypted, []byte(signature))
+	return data, nil
+}
 
-	var database database.Database
+// Deletes the contents of every attachment whose id isn't in the given list, returning
+// how many were deleted
+func (s *Storage) PruneAttachments(keep []string) int {
+	pruned := 0
 
-	err = utils.Degob(unsigned, &database)
+	for id := range s.Attachments {
+		if !slices.Contains(keep, id) {
+			delete(s.Attachments, id)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+// Gets the size of the Storage once written onto an image (in bytes)
+func (s *Storage) Size() (int, error) {
+	gobbed, err := utils.Gobify(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(gobbed), nil
+}
+
+// Gets a copy of the database's encryption key from the Storage's session, which the caller
+// should clear once it's done with it
+func (s *Storage) encryptionKey() ([]byte, error) {
+	keyData, err := key.Get(s.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &database, nil
+	return keyData.Key, nil
+}
+
+// Hashes the password, along with the key file's contents if the Storage requires one, into
+// the key kept in the keyring
+func (s *Storage) HashPassword(password, keyFilePath string) ([]byte, error) {
+	payload := []byte(password)
+
+	if s.RequiresKeyFile {
+		if len(keyFilePath) == 0 {
+			return nil, fmt.Errorf("a key file is required to unlock this file")
+		}
+
+		keyFileHash, err := crypto.HashFile(keyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the key file: %v", err)
+		}
+
+		payload = append(payload, keyFileHash...)
+	} else if len(keyFilePath) > 0 {
+		return nil, fmt.Errorf("this file doesn't use a key file")
+	}
+
+	return crypto.Hash(payload, s.PasswordSalt)
 }
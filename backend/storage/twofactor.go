@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"imcrypt_v3/backend/crypto"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Seconds per TOTP time step
+const totpPeriod = 30
+
+// Encrypts and stores the vault's TFA secret with the database's encryption key
+func (s *Storage) SetTwoFactorSecret(secret string) error {
+	encryptionKey, err := s.encryptionKey()
+	if err != nil {
+		return err
+	}
+	defer clear(encryptionKey)
+
+	encrypted, err := crypto.Encrypt([]byte(secret), encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	s.EncryptedTwoFactorSecret = encrypted
+	s.TwoFactorSecret = nil
+
+	return nil
+}
+
+// Decrypts the vault's TFA secret with the key in the keyring
+func (s *Storage) GetTwoFactorSecret() (string, error) {
+	encryptionKey, err := s.encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	defer clear(encryptionKey)
+
+	return s.decryptTwoFactorSecret(encryptionKey)
+}
+
+// Decrypts the vault's TFA secret with the given database key, for when the key is being
+// held back from the keyring until the second factor is verified
+func (s *Storage) GetTwoFactorSecretWith(databaseKey []byte) (string, error) {
+	return s.decryptTwoFactorSecret(databaseKey)
+}
+
+// Validates a TOTP code against the secret, allowing a step of clock skew either way. A code
+// is only accepted once: its time step must come after the last accepted one.
+func (s *Storage) ValidateTwoFactorCode(secret, code string, now time.Time) (bool, error) {
+	current := now.Unix() / totpPeriod
+
+	for step := current - 1; step <= current+1; step++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if !hmac.Equal([]byte(expected), []byte(code)) {
+			continue
+		}
+
+		if step <= s.TwoFactorLastStep {
+			return false, fmt.Errorf("that code has already been used, wait for the next one")
+		}
+
+		s.TwoFactorLastStep = step
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Checks if TFA has been set up, whether it's been confirmed or not
+func (s *Storage) HasTwoFactorSecret() bool {
+	return s.EncryptedTwoFactorSecret != nil || s.TwoFactorSecret != nil
+}
+
+// Checks if the TFA secret is still stored in the clear, as older storages did
+func (s *Storage) HasPlainTwoFactorSecret() bool {
+	return s.TwoFactorSecret != nil
+}
+
+// Forgets the TFA secret, its confirmation and its recovery codes
+func (s *Storage) ClearTwoFactor() {
+	s.EncryptedTwoFactorSecret = nil
+	s.TwoFactorSecret = nil
+	s.TwoFactorConfirmed = nil
+	s.ClearRecoveryCodes()
+}
+
+func (s *Storage) decryptTwoFactorSecret(encryptionKey []byte) (string, error) {
+	if s.TwoFactorSecret != nil {
+		return string(s.TwoFactorSecret), nil
+	}
+
+	if s.EncryptedTwoFactorSecret == nil {
+		return "", fmt.Errorf("two-factor authentication isn't set up")
+	}
+
+	secret, err := crypto.Decrypt(s.EncryptedTwoFactorSecret, encryptionKey)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt the two-factor secret: %v", err)
+	}
+
+	return string(secret), nil
+}
@@ -0,0 +1,189 @@
+package twofactor
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Google Authenticator's protobuf enums, as found in otpauth-migration:// payloads
+var migrationAlgorithms = map[uint64]string{0: "SHA1", 1: "SHA1", 2: "SHA256", 3: "SHA512"}
+var migrationDigits = map[uint64]int{0: 6, 1: 6, 2: 8}
+var migrationKinds = map[uint64]string{0: TOTP_KIND, 1: HOTP_KIND, 2: TOTP_KIND}
+
+var errTruncated = errors.New("the migration payload is truncated")
+
+// Parses every secret in a Google Authenticator otpauth-migration:// export
+func ParseMigration(s string) ([]Params, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || !strings.EqualFold(u.Scheme, "otpauth-migration") {
+		return nil, fmt.Errorf("unable to parse the otpauth-migration URI")
+	}
+
+	// The data is standard base64, but is sometimes left unescaped with spaces for pluses
+	data := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the migration data must be base64 encoded")
+	}
+
+	secrets := []Params{}
+
+	// MigrationPayload: repeated OtpParameters otp_parameters = 1, the rest is batch info
+	err = readMessage(payload, func(field uint64, value []byte, _ uint64) error {
+		if field != 1 || value == nil {
+			return nil
+		}
+
+		p, err := parseMigrationParams(value)
+		if err != nil {
+			return err
+		}
+
+		secrets = append(secrets, p)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("the migration payload doesn't contain any secrets")
+	}
+
+	return secrets, nil
+}
+
+// Parses an OtpParameters message
+func parseMigrationParams(message []byte) (Params, error) {
+	var secret []byte
+	var algorithm, digits, kind uint64
+	p := Params{}
+
+	err := readMessage(message, func(field uint64, value []byte, number uint64) error {
+		switch field {
+		case 1:
+			secret = value
+		case 2:
+			p.Account = string(value)
+		case 3:
+			p.Issuer = string(value)
+		case 4:
+			algorithm = number
+		case 5:
+			digits = number
+		case 6:
+			kind = number
+		case 7:
+			p.Counter = int64(number)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Params{}, err
+	}
+
+	var known bool
+
+	if p.Algorithm, known = migrationAlgorithms[algorithm]; !known {
+		return Params{}, fmt.Errorf("unsupported algorithm in the migration payload")
+	}
+
+	if p.Digits, known = migrationDigits[digits]; !known {
+		return Params{}, fmt.Errorf("unsupported digit count in the migration payload")
+	}
+
+	if p.Kind, known = migrationKinds[kind]; !known {
+		return Params{}, fmt.Errorf("unsupported code type in the migration payload")
+	}
+
+	// The name is usually "Issuer:Account", with the issuer repeated
+	if issuer, account, found := strings.Cut(p.Account, ":"); found {
+		p.Account = strings.TrimSpace(account)
+
+		if len(p.Issuer) == 0 {
+			p.Issuer = issuer
+		}
+	}
+
+	p.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+
+	return normalize(p)
+}
+
+// Walks the fields of a protobuf message, passing length-delimited fields as value and
+// varint fields as number. Fixed-width fields are skipped.
+func readMessage(b []byte, fn func(field uint64, value []byte, number uint64) error) error {
+	for len(b) > 0 {
+		tag, n := readVarint(b)
+		if n == 0 {
+			return errTruncated
+		}
+		b = b[n:]
+
+		field, wireType := tag>>3, tag&7
+
+		switch wireType {
+		case 0:
+			number, n := readVarint(b)
+			if n == 0 {
+				return errTruncated
+			}
+			b = b[n:]
+
+			if err := fn(field, nil, number); err != nil {
+				return err
+			}
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+
+			if len(b) < size {
+				return errTruncated
+			}
+			b = b[size:]
+		case 2:
+			length, n := readVarint(b)
+			if n == 0 || uint64(len(b)-n) < length {
+				return errTruncated
+			}
+
+			value := b[n : n+int(length)]
+			b = b[n+int(length):]
+
+			if err := fn(field, value, 0); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported field in the migration payload")
+		}
+	}
+
+	return nil
+}
+
+// Reads a varint, returning it and the number of bytes read (0 if it's truncated)
+func readVarint(b []byte) (uint64, int) {
+	var value uint64
+
+	for i := 0; i < len(b) && i < 10; i++ {
+		value |= uint64(b[i]&0x7f) << (7 * i)
+
+		if b[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+
+	return 0, 0
+}
@@ -0,0 +1,309 @@
+package twofactor
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	TOTP_KIND  = "TOTP"
+	HOTP_KIND  = "HOTP"
+	STEAM_KIND = "STEAM"
+)
+
+// Steam Guard codes are always 5 characters long, regardless of what's requested
+const steamDigits = 5
+
+// Everything needed to generate one-time codes
+type Params struct {
+	Kind      string `json:"kind"`      // TOTP, HOTP or STEAM
+	Secret    string `json:"secret"`    // base32, without padding
+	Issuer    string `json:"issuer"`    // e.g. GitHub
+	Account   string `json:"account"`   // e.g. someone@example.com
+	Algorithm string `json:"algorithm"` // SHA1, SHA256 or SHA512
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`  // seconds, TOTP and STEAM only
+	Counter   int64  `json:"counter"` // HOTP only
+}
+
+// Parsed params, along with the URI to store them as an item's two factor secret
+type Entry struct {
+	Params Params `json:"params"`
+	URI    string `json:"uri"`
+}
+
+type Code struct {
+	Code      string `json:"code"`
+	Next      string `json:"next"`      // the code that follows
+	Remaining int    `json:"remaining"` // seconds until Next takes over, 0 for HOTP
+	Period    int    `json:"period"`    // 0 for HOTP
+	Counter   int64  `json:"counter"`   // the counter Code was generated with, HOTP only
+}
+
+// Parses a stored two factor secret, which is either a bare base32 secret (TOTP with the
+// usual defaults), an otpauth:// URI, or a steam:// URI
+func Parse(s string) (Params, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	switch {
+	case strings.HasPrefix(lower, "otpauth://"):
+		return parseURI(s)
+	case strings.HasPrefix(lower, "steam://"):
+		return normalize(Params{Kind: STEAM_KIND, Secret: s[len("steam://"):]})
+	}
+
+	return normalize(Params{Kind: TOTP_KIND, Secret: s})
+}
+
+// Parses a single secret or URI, or every secret in an otpauth-migration:// export
+func ParseAll(s string) ([]Entry, error) {
+	var secrets []Params
+
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(s)), "otpauth-migration://") {
+		var err error
+
+		secrets, err = ParseMigration(s)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+
+		secrets = []Params{p}
+	}
+
+	entries := []Entry{}
+	for _, p := range secrets {
+		entries = append(entries, Entry{Params: p, URI: p.URI()})
+	}
+
+	return entries, nil
+}
+
+func parseURI(s string) (Params, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Params{}, fmt.Errorf("unable to parse the otpauth URI: %v", err)
+	}
+
+	q := u.Query()
+	p := Params{
+		Kind:      strings.ToUpper(u.Host),
+		Secret:    q.Get("secret"),
+		Issuer:    q.Get("issuer"),
+		Algorithm: q.Get("algorithm"),
+	}
+
+	// The label is "Issuer:Account" or just "Account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		p.Account = strings.TrimSpace(account)
+
+		if len(p.Issuer) == 0 {
+			p.Issuer = issuer
+		}
+	} else {
+		p.Account = label
+	}
+
+	if strings.EqualFold(q.Get("encoder"), "steam") {
+		p.Kind = STEAM_KIND
+	}
+
+	if digits := q.Get("digits"); len(digits) > 0 {
+		if p.Digits, err = strconv.Atoi(digits); err != nil {
+			return Params{}, fmt.Errorf("the digits must be a number")
+		}
+	}
+
+	if period := q.Get("period"); len(period) > 0 {
+		if p.Period, err = strconv.Atoi(period); err != nil {
+			return Params{}, fmt.Errorf("the period must be a number")
+		}
+	}
+
+	if counter := q.Get("counter"); len(counter) > 0 {
+		if p.Counter, err = strconv.ParseInt(counter, 10, 64); err != nil {
+			return Params{}, fmt.Errorf("the counter must be a number")
+		}
+	}
+
+	return normalize(p)
+}
+
+// Fills in defaults and validates the params
+func normalize(p Params) (Params, error) {
+	p.Kind = strings.ToUpper(strings.TrimSpace(p.Kind))
+	p.Secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(strings.TrimSpace(p.Secret), " ", ""), "="))
+	p.Algorithm = strings.ToUpper(strings.TrimSpace(p.Algorithm))
+	p.Issuer = strings.TrimSpace(p.Issuer)
+
+	if p.Kind != TOTP_KIND && p.Kind != HOTP_KIND && p.Kind != STEAM_KIND {
+		return Params{}, fmt.Errorf("the code type must be %s, %s, or %s", TOTP_KIND, HOTP_KIND, STEAM_KIND)
+	}
+
+	if len(p.Secret) == 0 {
+		return Params{}, fmt.Errorf("the secret cannot be empty")
+	}
+
+	if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(p.Secret); err != nil {
+		return Params{}, fmt.Errorf("the secret must be base32 encoded")
+	}
+
+	if len(p.Algorithm) == 0 {
+		p.Algorithm = "SHA1"
+	}
+
+	if _, err := algorithm(p.Algorithm); err != nil {
+		return Params{}, err
+	}
+
+	if p.Kind == STEAM_KIND {
+		p.Digits = steamDigits
+	} else if p.Digits == 0 {
+		p.Digits = 6
+	}
+
+	if p.Digits < 5 || p.Digits > 10 {
+		return Params{}, fmt.Errorf("the digits must be between 5 and 10")
+	}
+
+	if p.Kind == HOTP_KIND {
+		p.Period = 0
+	} else if p.Period == 0 {
+		p.Period = 30
+	}
+
+	if p.Period < 0 {
+		return Params{}, fmt.Errorf("the period must be > 0")
+	}
+
+	if p.Counter < 0 {
+		return Params{}, fmt.Errorf("the counter must be >= 0")
+	}
+
+	return p, nil
+}
+
+// Generates the code for the given time (TOTP/STEAM) or the current counter (HOTP)
+func (p Params) Generate(t time.Time) (Code, error) {
+	alg, err := algorithm(p.Algorithm)
+	if err != nil {
+		return Code{}, err
+	}
+
+	encoder := otp.EncoderDefault
+	if p.Kind == STEAM_KIND {
+		encoder = otp.EncoderSteam
+	}
+
+	if p.Kind == HOTP_KIND {
+		opts := hotp.ValidateOpts{
+			Digits:    otp.Digits(p.Digits),
+			Algorithm: alg,
+			Encoder:   encoder,
+		}
+
+		code, err := hotp.GenerateCodeCustom(p.Secret, uint64(p.Counter), opts)
+		if err != nil {
+			return Code{}, err
+		}
+
+		next, err := hotp.GenerateCodeCustom(p.Secret, uint64(p.Counter+1), opts)
+		if err != nil {
+			return Code{}, err
+		}
+
+		return Code{Code: code, Next: next, Counter: p.Counter}, nil
+	}
+
+	opts := totp.ValidateOpts{
+		Period:    uint(p.Period),
+		Digits:    otp.Digits(p.Digits),
+		Algorithm: alg,
+		Encoder:   encoder,
+	}
+
+	code, err := totp.GenerateCodeCustom(p.Secret, t, opts)
+	if err != nil {
+		return Code{}, err
+	}
+
+	period := time.Duration(p.Period) * time.Second
+	next, err := totp.GenerateCodeCustom(p.Secret, t.Add(period), opts)
+	if err != nil {
+		return Code{}, err
+	}
+
+	return Code{
+		Code:      code,
+		Next:      next,
+		Remaining: p.Period - int(t.Unix()%int64(p.Period)),
+		Period:    p.Period,
+	}, nil
+}
+
+// Serializes the params as an otpauth:// URI
+func (p Params) URI() string {
+	kind := strings.ToLower(p.Kind)
+	q := url.Values{}
+
+	q.Set("secret", p.Secret)
+
+	if p.Kind == STEAM_KIND {
+		kind = "totp"
+		q.Set("encoder", "steam")
+	}
+
+	if len(p.Issuer) > 0 {
+		q.Set("issuer", p.Issuer)
+	}
+
+	q.Set("algorithm", p.Algorithm)
+	q.Set("digits", strconv.Itoa(p.Digits))
+
+	if p.Kind == HOTP_KIND {
+		q.Set("counter", strconv.FormatInt(p.Counter, 10))
+	} else {
+		q.Set("period", strconv.Itoa(p.Period))
+	}
+
+	label := p.Account
+	if len(p.Issuer) > 0 {
+		label = p.Issuer + ":" + p.Account
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     kind,
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+func algorithm(name string) (otp.Algorithm, error) {
+	switch name {
+	case "SHA1":
+		return otp.AlgorithmSHA1, nil
+	case "SHA256":
+		return otp.AlgorithmSHA256, nil
+	case "SHA512":
+		return otp.AlgorithmSHA512, nil
+	}
+
+	return 0, fmt.Errorf("the algorithm must be SHA1, SHA256, or SHA512")
+}
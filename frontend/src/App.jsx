@@ -16,6 +16,14 @@ export default function App({ children }) {
 			}
 		})
 
+		window.runtime.EventsOn("e_breachcheck", err => {
+			useToast().showError(`Couldn't check the password against the breach corpus: ${err}`)
+		})
+
+		window.runtime.EventsOn("e_securitykey", () => {
+			useToast().showInfo("Touch your security key")
+		})
+
 		function handleMouseDown(e) {
 			mouseDownTarget.current = e.target
 		}
@@ -2,7 +2,7 @@ import { useState, useEffect } from "react"
 import Input from "../../components/Input"
 import Button from "../../components/Button"
 import RightArrow from "../../components/svg/RightArrow"
-import { DeleteTwoFactorSecret, ValidateTwoFactorRecoveryCode } from "../../../wailsjs/go/main/App"
+import { ValidateTwoFactorRecoveryCode } from "../../../wailsjs/go/main/App"
 import { usePath } from "crossroad"
 import { useConfirm } from "../../components/Confirm"
 import useFocusTrap from "../../components/FocusTrap"
@@ -16,7 +16,7 @@ export default function DecryptLost() {
 	const toast = useToast()
 
 	async function handleSubmit() {
-		const [valErr, isValid] = await ValidateTwoFactorRecoveryCode(input)
+		const [valErr, isValid, remaining] = await ValidateTwoFactorRecoveryCode(input)
 
 		if (valErr) {
 			return toast.showError(valErr)
@@ -26,27 +26,16 @@ export default function DecryptLost() {
 			return toast.showError("Invalid input")
 		}
 
-		confirm.open({
-			title: "Are you sure want to continue?",
-			message: "Your two-factor authentication will be erased and you'll have to set it up again.",
-			confirmText: "Continue",
-			onConfirm: async () => {
-				const [err] = await DeleteTwoFactorSecret()
-
-				if (err) {
-					return toast.showError(err)
-				}
+		const [loadErr] = await useDatabaseState.getState().load()
+		if (loadErr) throw loadErr
 
-				toast.showInfo("TFA has been erased from this file.")
-				useDatabaseState.getState().load()
-				setPath("/home", { mode: "replace" })
-			}
-		})
+		toast.showInfo(`Recovery code used, ${remaining} left.`)
+		setPath("/home", { mode: "replace" })
 	}
 
 	function handleLostRecoveryCode() {
 		confirm.open({
-			message: "Unfortunately there's no way to recover your data without the TFA recovery code.",
+			message: "Unfortunately there's no way to recover your data without a TFA recovery code.",
 			noCancel: true
 		})
 	}
@@ -57,7 +46,7 @@ export default function DecryptLost() {
 
 	return (
 		<div className="page" id="decrypt-lost">
-			<h1>Enter the recovery code given to you when you setup TFA.</h1>
+			<h1>Enter one of the recovery codes given to you when you setup TFA.</h1>
 			<div className="code-input-section">
 				<Input className="code-input" value={input} onChange={e => setInput(e.target.value)} onSubmit={handleSubmit} />
 				<Button className="submit-btn" onClick={handleSubmit}>
@@ -65,7 +54,7 @@ export default function DecryptLost() {
 				</Button>
 			</div>
 			<button className="gradient-text option-btn" onClick={handleLostRecoveryCode}>
-				I don't have the recovery code
+				I don't have a recovery code
 			</button>
 			<button className="gradient-text option-btn" onClick={() => setPath("/decrypt/auth", { mode: "replace" })}>
 				Nevermind, I have access to my authenticator
@@ -5,7 +5,9 @@ import RightArrow from "../../components/svg/RightArrow"
 import {
 	CloseSession,
 	HasTwoFactorAuthentication,
+	OpenFileDialog,
 	ReadLoadedImage,
+	RequiresKeyFile,
 	UnlockLoadedImage
 } from "../../../wailsjs/go/main/App"
 import { usePath } from "crossroad"
@@ -18,6 +20,8 @@ export default function DecryptText() {
 	const [value, setValue] = useState("")
 	const [error, setError] = useState(false)
 	const [hint, setHint] = useState("")
+	const [needsKeyFile, setNeedsKeyFile] = useState(false)
+	const [keyFile, setKeyFile] = useState("")
 	const [, setPath] = usePath()
 	const toast = useToast()
 
@@ -29,11 +33,22 @@ export default function DecryptText() {
 			return
 		}
 
-		const [authErr] = await UnlockLoadedImage(value)
+		if (needsKeyFile && !keyFile) {
+			setError(true)
+			setHint("This file requires a key file")
+
+			return
+		}
+
+		const [authErr] = await UnlockLoadedImage(value, keyFile)
 
 		if (authErr) {
 			setError(true)
-			setHint("Failed to authenticate")
+			setHint(
+				authErr.startsWith("too many failed attempts") || authErr.includes("security key")
+					? authErr
+					: "Failed to authenticate"
+			)
 
 			return console.error("auth err:", authErr)
 		}
@@ -66,6 +81,18 @@ export default function DecryptText() {
 		}
 	}
 
+	async function handleKeyFile() {
+		const [err, path] = await OpenFileDialog("Select the key file", "*")
+		if (err) throw new Error(err)
+
+		setKeyFile(path)
+
+		if (error) {
+			setError(false)
+			setHint("")
+		}
+	}
+
 	async function handleRelease() {
 		await CloseSession()
 		useLoadedImageState.setState({ binary: null })
@@ -76,6 +103,12 @@ export default function DecryptText() {
 
 	useEffect(() => {
 		useFocusTrap("#decrypt")
+		;(async function () {
+			const [err, required] = await RequiresKeyFile()
+			if (err) throw new Error(err)
+
+			setNeedsKeyFile(required)
+		})()
 		if (!binary) {
 			;(async function () {
 				const [err, binary] = await ReadLoadedImage()
@@ -103,6 +136,11 @@ export default function DecryptText() {
 					<RightArrow />
 				</Button>
 			</div>
+			{needsKeyFile && (
+				<button className="option-btn gradient-text" onClick={handleKeyFile}>
+					{keyFile ? `Key file: ${keyFile.split(/[\\/]/).pop()}` : "Choose your key file"}
+				</button>
+			)}
 			<button className="option-btn gradient-text" onClick={handleRelease}>
 				Choose a different file
 			</button>
@@ -13,7 +13,7 @@ export default function EncryptAuth() {
 	const [img, setImg] = useState("")
 	const [input, setInput] = useState("")
 	const [codeInput, setCodeInput] = useState("")
-	const [recovery, setRecovery] = useState("")
+	const [recovery, setRecovery] = useState([])
 	const [, setPath] = usePath()
 	const toast = useToast()
 	const confirm = useConfirm()
@@ -44,8 +44,8 @@ export default function EncryptAuth() {
 		}
 
 		confirm.open({
-			title: "Success! Now, save the following secret code in case you lose access to your authenticator app.",
-			message: <textarea value={recovery}></textarea>,
+			title: "Success! Now, save the following recovery codes in case you lose access to your authenticator app. Each one can only be used once.",
+			message: <textarea value={recovery.join("\n")}></textarea>,
 			confirmText: "Done",
 			onConfirm: async () => {
 				const [loadErr] = await useDatabaseState.getState().load()
@@ -1,7 +1,7 @@
 import { useState, useEffect } from "react"
 import Input from "../../components/Input"
 import Button from "../../components/Button"
-import { InitializeStorage, ReadLoadedImage, CloseSession } from "../../../wailsjs/go/main/App"
+import { InitializeStorage, ReadLoadedImage, CloseSession, OpenFileDialog } from "../../../wailsjs/go/main/App"
 import { usePath } from "crossroad"
 import { useLoadedImageState, useLoginPanelState, useRulesetPanelState } from "../../store"
 import { useConfirm } from "../../components/Confirm"
@@ -17,6 +17,7 @@ export default function EncryptText() {
 	const [secondValue, setSecondValue] = useState("")
 	const [secondError, setSecondError] = useState(false)
 	const [secondHint, setSecondHint] = useState("")
+	const [keyFile, setKeyFile] = useState("")
 
 	async function handleSubmit() {
 		if (firstValue !== secondValue) {
@@ -33,12 +34,12 @@ export default function EncryptText() {
 			return
 		}
 
-		const [err] = await InitializeStorage(firstValue)
+		const [err] = await InitializeStorage(firstValue, keyFile, false)
 
 		if (err) throw new Error(err)
 
 		confirm.open({
-			message: "Would you like to set up two-factor authentication?",
+			message: "Would you like to set up two-factor authentication? It only guards logging in through this app. Anyone with the image and your password can still decrypt it elsewhere, so require a security key or a key file if you want a second factor that's part of the encryption.",
 			confirmText: "Yes",
 			cancelText: "No",
 			onConfirm: () => setPath("/encrypt/auth", { mode: "replace" }),
@@ -74,6 +75,17 @@ export default function EncryptText() {
 		}
 	}
 
+	async function handleKeyFile() {
+		if (keyFile) {
+			return setKeyFile("")
+		}
+
+		const [err, path] = await OpenFileDialog("Select a key file", "*")
+		if (err) throw new Error(err)
+
+		setKeyFile(path)
+	}
+
 	async function handleRelease() {
 		await CloseSession()
 		useLoadedImageState.setState({ binary: null })
@@ -118,6 +130,9 @@ export default function EncryptText() {
 			<Button onClick={handleSubmit} className="submit-button">
 				Submit
 			</Button>
+			<button className="option-btn gradient-text" onClick={handleKeyFile}>
+				{keyFile ? `Don't require ${keyFile.split(/[\\/]/).pop()}` : "Also require a key file"}
+			</button>
 			<button className="option-btn gradient-text" onClick={handleRelease}>
 				Choose a different file
 			</button>
@@ -163,13 +163,15 @@ export default function Home() {
 		}
 	}
 
-	async function handleCopyToClipboard(data, name = "") {
-		const result = await window.runtime.ClipboardSetText(data)
+	async function handleCopyToClipboard(itemId, field, name = "") {
+		const [err, secret] = await useDatabaseState.getState().copyItemField(itemId, field)
 
-		if (result) {
-			toast.showInfo(`Copied ${name ? `${name} ` : ""}to clipboard!`)
-		} else {
+		if (err) {
 			toast.showError(`Failed to copy ${name ? `${name} ` : ""}to clipboard.`)
+		} else if (!secret) {
+			toast.showInfo(`Copied ${name ? `${name} ` : ""}to clipboard, but your clipboard manager may keep it in its history.`)
+		} else {
+			toast.showInfo(`Copied ${name ? `${name} ` : ""}to clipboard!`)
 		}
 	}
 
@@ -687,7 +689,7 @@ export default function Home() {
 														prefixIcon={AtSign}
 														label="Email"
 														value={item.email}>
-														<Button onClick={() => handleCopyToClipboard(item.email, "email")} className="copy-button">
+														<Button onClick={() => handleCopyToClipboard(activeItem, "email", "email")} className="copy-button">
 															{state.copySuccess ? <ClipboardCheck /> : <Clipboard />}
 														</Button>
 													</Input>
@@ -699,14 +701,14 @@ export default function Home() {
 														label="Username"
 														value={item.username}>
 														<Button
-															onClick={() => handleCopyToClipboard(item.username, "username")}
+															onClick={() => handleCopyToClipboard(activeItem, "username", "username")}
 															className="copy-button">
 															{state.copySuccess ? <ClipboardCheck /> : <Clipboard />}
 														</Button>
 													</Input>
 													<Input className="password-box" prefixIcon={Lock} label="Password" value={item.password}>
 														<Button
-															onClick={() => handleCopyToClipboard(item.password, "password")}
+															onClick={() => handleCopyToClipboard(activeItem, "password", "password")}
 															className="copy-button">
 															{state.copySuccess ? <ClipboardCheck /> : <Clipboard />}
 														</Button>
@@ -726,7 +728,7 @@ export default function Home() {
 															label="TFA Authentication Secret"
 															value={item.twoFactorSecret}>
 															<Button
-																onClick={() => handleCopyToClipboard(item.twoFactorSecret, "TFA secret")}
+																onClick={() => handleCopyToClipboard(activeItem, "twoFactorSecret", "TFA secret")}
 																className="copy-button">
 																{state.copySuccess ? <ClipboardCheck /> : <Clipboard />}
 															</Button>
@@ -9,7 +9,8 @@ import {
 	DeleteGroupsById,
 	ValidatePassword,
 	GeneratePassword,
-	GetFaviconURL
+	GetFaviconURL,
+	CopyItemField
 } from "../wailsjs/go/main/App"
 import isEqual from "lodash.isequal"
 import { sortString } from "./utils"
@@ -277,6 +278,17 @@ export const useDatabaseState = create((set, get) => ({
 		return [, groupIds]
 	},
 
+	// .. COPY
+	copyItemField: async (itemId, field) => {
+		const [err, updatedDatabase, secret] = await CopyItemField(itemId, field)
+
+		if (err) return [new Error(err)]
+
+		get()._set(updatedDatabase)
+
+		return [null, secret]
+	},
+
 	// .. UPDATE
 	updateItemsById: async updates => {
 		const [err, updatedDatabase] = await UpdateItemsById(updates)
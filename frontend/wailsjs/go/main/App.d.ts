@@ -2,29 +2,77 @@
 // This file is automatically generated. DO NOT EDIT
 import {database} from '../models';
 
+export function AddAttachment(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function AddKeySlot(arg1:string,arg2:string,arg3:string):Promise<Array<any>>;
+
+export function AddSecurityKey(arg1:string,arg2:string,arg3:string):Promise<Array<any>>;
+
+export function AuditVault():Promise<Array<any>>;
+
+export function AutoTypeItem(arg1:string):Promise<Array<any>>;
+
+export function ChangeMasterPassword(arg1:string,arg2:string,arg3:string,arg4:string):Promise<Array<any>>;
+
+export function CheckBreaches():Promise<Array<any>>;
+
 export function CloseSession():Promise<Array<any>>;
 
+export function CopyItemField(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function CreateRecoveryPhrase():Promise<Array<any>>;
+
 export function DeleteGroupsById(arg1:Array<string>):Promise<Array<any>>;
 
 export function DeleteItemsById(arg1:Array<string>):Promise<Array<any>>;
 
 export function DeleteTwoFactorSecret():Promise<Array<any>>;
 
+export function EmptyTrash():Promise<Array<any>>;
+
+export function ExportDatabase():Promise<Array<any>>;
+
+export function ExportSSHKey(arg1:string):Promise<Array<any>>;
+
 export function FocusWindow():Promise<void>;
 
 export function GeneratePassword(arg1:database.Ruleset,arg2:Array<string>):Promise<Array<any>>;
 
+export function GenerateSSHKey(arg1:string,arg2:number,arg3:string,arg4:string):Promise<Array<any>>;
+
+export function GenerateSecurityAnswer(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function GenerateSharingKeyPair():Promise<Array<any>>;
+
 export function GenerateTwoFactorSecret():Promise<Array<any>>;
 
+export function GetAttachment(arg1:string,arg2:string):Promise<Array<any>>;
+
 export function GetDatabase():Promise<Array<any>>;
 
+export function GetExpiryAlerts():Promise<Array<any>>;
+
 export function GetFaviconURL(arg1:string):Promise<Array<any>>;
 
+export function GetHistoryStatus():Promise<Array<any>>;
+
+export function GetItemTOTP(arg1:string):Promise<Array<any>>;
+
+export function GetLockoutStatus():Promise<Array<any>>;
+
+export function GetOAuthDependents(arg1:string):Promise<Array<any>>;
+
+export function GetReusedSecurityAnswers():Promise<Array<any>>;
+
+export function GetTwoFactorRecoveryCodeCount():Promise<Array<any>>;
+
 export function HasStorage():Promise<Array<any>>;
 
 export function HasTwoFactorAuthentication():Promise<Array<any>>;
 
-export function InitializeStorage(arg1:string):Promise<Array<any>>;
+export function ImportDatabase():Promise<Array<any>>;
+
+export function InitializeStorage(arg1:string,arg2:string,arg3:boolean):Promise<Array<any>>;
 
 export function InsertGroups(arg1:Array<database.Group>):Promise<Array<any>>;
 
@@ -32,20 +80,74 @@ export function InsertItems(arg1:Array<database.InsertItemsArg>):Promise<Array<a
 
 export function IsAuthenticated():Promise<Array<any>>;
 
+export function IsPasswordResetRequired():Promise<Array<any>>;
+
+export function ListKeySlots():Promise<Array<any>>;
+
+export function ListVaults():Promise<Array<any>>;
+
 export function LoadImage(arg1:string):Promise<Array<any>>;
 
+export function MigrateTwoFactorSecrets(arg1:string):Promise<Array<any>>;
+
 export function OpenFileDialog(arg1:string,arg2:string):Promise<Array<any>>;
 
 export function OpenURLInBrowser(arg1:string):Promise<void>;
 
+export function ParseTwoFactor(arg1:string):Promise<Array<any>>;
+
+export function ParseTwoFactorQR(arg1:string):Promise<Array<any>>;
+
 export function ReadLoadedImage():Promise<Array<any>>;
 
-export function UnlockLoadedImage(arg1:string):Promise<Array<any>>;
+export function Redo():Promise<Array<any>>;
+
+export function RegenerateTwoFactorRecoveryCodes():Promise<Array<any>>;
+
+export function RememberSession(arg1:boolean):Promise<Array<any>>;
+
+export function RemoveAttachment(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function RemoveKeySlot(arg1:string):Promise<Array<any>>;
+
+export function RemoveSecurityKey(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function RequiresKeyFile():Promise<Array<any>>;
+
+export function RequiresSecurityKey():Promise<Array<any>>;
+
+export function RestoreGroups(arg1:Array<string>):Promise<Array<any>>;
+
+export function RestoreItems(arg1:Array<string>):Promise<Array<any>>;
+
+export function ResumeSession():Promise<Array<any>>;
+
+export function RevealSecurityAnswer(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function SearchItems(arg1:string):Promise<Array<any>>;
+
+export function SetLockoutPolicy(arg1:number):Promise<Array<any>>;
+
+export function StartSSHAgent():Promise<Array<any>>;
+
+export function StopSSHAgent():Promise<Array<any>>;
+
+export function SwitchVault(arg1:string):Promise<Array<any>>;
+
+export function Undo():Promise<Array<any>>;
+
+export function UnlockLoadedImage(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function UnlockWithKeySlot(arg1:string,arg2:string):Promise<Array<any>>;
+
+export function UnlockWithRecoveryPhrase(arg1:string):Promise<Array<any>>;
 
 export function UpdateGroupsById(arg1:Array<database.GroupUpdate>):Promise<Array<any>>;
 
 export function UpdateItemsById(arg1:Array<database.ItemUpdate>):Promise<Array<any>>;
 
+export function UpdateSettings(arg1:database.SettingsUpdate):Promise<Array<any>>;
+
 export function ValidatePassword(arg1:string,arg2:database.Ruleset,arg3:Array<string>):Promise<Array<any>>;
 
 export function ValidateTwoFactorCode(arg1:string,arg2:boolean):Promise<Array<any>>;
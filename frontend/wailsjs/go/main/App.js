@@ -2,10 +2,46 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddAttachment(arg1, arg2) {
+  return window['go']['main']['App']['AddAttachment'](arg1, arg2);
+}
+
+export function AddKeySlot(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddKeySlot'](arg1, arg2, arg3);
+}
+
+export function AddSecurityKey(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddSecurityKey'](arg1, arg2, arg3);
+}
+
+export function AuditVault() {
+  return window['go']['main']['App']['AuditVault']();
+}
+
+export function AutoTypeItem(arg1) {
+  return window['go']['main']['App']['AutoTypeItem'](arg1);
+}
+
+export function ChangeMasterPassword(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ChangeMasterPassword'](arg1, arg2, arg3, arg4);
+}
+
+export function CheckBreaches() {
+  return window['go']['main']['App']['CheckBreaches']();
+}
+
 export function CloseSession() {
   return window['go']['main']['App']['CloseSession']();
 }
 
+export function CopyItemField(arg1, arg2) {
+  return window['go']['main']['App']['CopyItemField'](arg1, arg2);
+}
+
+export function CreateRecoveryPhrase() {
+  return window['go']['main']['App']['CreateRecoveryPhrase']();
+}
+
 export function DeleteGroupsById(arg1) {
   return window['go']['main']['App']['DeleteGroupsById'](arg1);
 }
@@ -18,6 +54,18 @@ export function DeleteTwoFactorSecret() {
   return window['go']['main']['App']['DeleteTwoFactorSecret']();
 }
 
+export function EmptyTrash() {
+  return window['go']['main']['App']['EmptyTrash']();
+}
+
+export function ExportDatabase() {
+  return window['go']['main']['App']['ExportDatabase']();
+}
+
+export function ExportSSHKey(arg1) {
+  return window['go']['main']['App']['ExportSSHKey'](arg1);
+}
+
 export function FocusWindow() {
   return window['go']['main']['App']['FocusWindow']();
 }
@@ -26,18 +74,62 @@ export function GeneratePassword(arg1, arg2) {
   return window['go']['main']['App']['GeneratePassword'](arg1, arg2);
 }
 
+export function GenerateSSHKey(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GenerateSSHKey'](arg1, arg2, arg3, arg4);
+}
+
+export function GenerateSecurityAnswer(arg1, arg2) {
+  return window['go']['main']['App']['GenerateSecurityAnswer'](arg1, arg2);
+}
+
+export function GenerateSharingKeyPair() {
+  return window['go']['main']['App']['GenerateSharingKeyPair']();
+}
+
 export function GenerateTwoFactorSecret() {
   return window['go']['main']['App']['GenerateTwoFactorSecret']();
 }
 
+export function GetAttachment(arg1, arg2) {
+  return window['go']['main']['App']['GetAttachment'](arg1, arg2);
+}
+
 export function GetDatabase() {
   return window['go']['main']['App']['GetDatabase']();
 }
 
+export function GetExpiryAlerts() {
+  return window['go']['main']['App']['GetExpiryAlerts']();
+}
+
 export function GetFaviconURL(arg1) {
   return window['go']['main']['App']['GetFaviconURL'](arg1);
 }
 
+export function GetHistoryStatus() {
+  return window['go']['main']['App']['GetHistoryStatus']();
+}
+
+export function GetItemTOTP(arg1) {
+  return window['go']['main']['App']['GetItemTOTP'](arg1);
+}
+
+export function GetLockoutStatus() {
+  return window['go']['main']['App']['GetLockoutStatus']();
+}
+
+export function GetOAuthDependents(arg1) {
+  return window['go']['main']['App']['GetOAuthDependents'](arg1);
+}
+
+export function GetReusedSecurityAnswers() {
+  return window['go']['main']['App']['GetReusedSecurityAnswers']();
+}
+
+export function GetTwoFactorRecoveryCodeCount() {
+  return window['go']['main']['App']['GetTwoFactorRecoveryCodeCount']();
+}
+
 export function HasStorage() {
   return window['go']['main']['App']['HasStorage']();
 }
@@ -46,8 +138,12 @@ export function HasTwoFactorAuthentication() {
   return window['go']['main']['App']['HasTwoFactorAuthentication']();
 }
 
-export function InitializeStorage(arg1) {
-  return window['go']['main']['App']['InitializeStorage'](arg1);
+export function ImportDatabase() {
+  return window['go']['main']['App']['ImportDatabase']();
+}
+
+export function InitializeStorage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InitializeStorage'](arg1, arg2, arg3);
 }
 
 export function InsertGroups(arg1) {
@@ -62,10 +158,26 @@ export function IsAuthenticated() {
   return window['go']['main']['App']['IsAuthenticated']();
 }
 
+export function IsPasswordResetRequired() {
+  return window['go']['main']['App']['IsPasswordResetRequired']();
+}
+
+export function ListKeySlots() {
+  return window['go']['main']['App']['ListKeySlots']();
+}
+
+export function ListVaults() {
+  return window['go']['main']['App']['ListVaults']();
+}
+
 export function LoadImage(arg1) {
   return window['go']['main']['App']['LoadImage'](arg1);
 }
 
+export function MigrateTwoFactorSecrets(arg1) {
+  return window['go']['main']['App']['MigrateTwoFactorSecrets'](arg1);
+}
+
 export function OpenFileDialog(arg1, arg2) {
   return window['go']['main']['App']['OpenFileDialog'](arg1, arg2);
 }
@@ -74,12 +186,100 @@ export function OpenURLInBrowser(arg1) {
   return window['go']['main']['App']['OpenURLInBrowser'](arg1);
 }
 
+export function ParseTwoFactor(arg1) {
+  return window['go']['main']['App']['ParseTwoFactor'](arg1);
+}
+
+export function ParseTwoFactorQR(arg1) {
+  return window['go']['main']['App']['ParseTwoFactorQR'](arg1);
+}
+
 export function ReadLoadedImage() {
   return window['go']['main']['App']['ReadLoadedImage']();
 }
 
-export function UnlockLoadedImage(arg1) {
-  return window['go']['main']['App']['UnlockLoadedImage'](arg1);
+export function Redo() {
+  return window['go']['main']['App']['Redo']();
+}
+
+export function RegenerateTwoFactorRecoveryCodes() {
+  return window['go']['main']['App']['RegenerateTwoFactorRecoveryCodes']();
+}
+
+export function RememberSession(arg1) {
+  return window['go']['main']['App']['RememberSession'](arg1);
+}
+
+export function RemoveAttachment(arg1, arg2) {
+  return window['go']['main']['App']['RemoveAttachment'](arg1, arg2);
+}
+
+export function RemoveKeySlot(arg1) {
+  return window['go']['main']['App']['RemoveKeySlot'](arg1);
+}
+
+export function RemoveSecurityKey(arg1, arg2) {
+  return window['go']['main']['App']['RemoveSecurityKey'](arg1, arg2);
+}
+
+export function RequiresKeyFile() {
+  return window['go']['main']['App']['RequiresKeyFile']();
+}
+
+export function RequiresSecurityKey() {
+  return window['go']['main']['App']['RequiresSecurityKey']();
+}
+
+export function RestoreGroups(arg1) {
+  return window['go']['main']['App']['RestoreGroups'](arg1);
+}
+
+export function RestoreItems(arg1) {
+  return window['go']['main']['App']['RestoreItems'](arg1);
+}
+
+export function ResumeSession() {
+  return window['go']['main']['App']['ResumeSession']();
+}
+
+export function RevealSecurityAnswer(arg1, arg2) {
+  return window['go']['main']['App']['RevealSecurityAnswer'](arg1, arg2);
+}
+
+export function SearchItems(arg1) {
+  return window['go']['main']['App']['SearchItems'](arg1);
+}
+
+export function SetLockoutPolicy(arg1) {
+  return window['go']['main']['App']['SetLockoutPolicy'](arg1);
+}
+
+export function StartSSHAgent() {
+  return window['go']['main']['App']['StartSSHAgent']();
+}
+
+export function StopSSHAgent() {
+  return window['go']['main']['App']['StopSSHAgent']();
+}
+
+export function SwitchVault(arg1) {
+  return window['go']['main']['App']['SwitchVault'](arg1);
+}
+
+export function Undo() {
+  return window['go']['main']['App']['Undo']();
+}
+
+export function UnlockLoadedImage(arg1, arg2) {
+  return window['go']['main']['App']['UnlockLoadedImage'](arg1, arg2);
+}
+
+export function UnlockWithKeySlot(arg1, arg2) {
+  return window['go']['main']['App']['UnlockWithKeySlot'](arg1, arg2);
+}
+
+export function UnlockWithRecoveryPhrase(arg1) {
+  return window['go']['main']['App']['UnlockWithRecoveryPhrase'](arg1);
 }
 
 export function UpdateGroupsById(arg1) {
@@ -90,6 +290,10 @@ export function UpdateItemsById(arg1) {
   return window['go']['main']['App']['UpdateItemsById'](arg1);
 }
 
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
+
 export function ValidatePassword(arg1, arg2, arg3) {
   return window['go']['main']['App']['ValidatePassword'](arg1, arg2, arg3);
 }
@@ -1,5 +1,67 @@
 export namespace database {
 	
+	export class Attachment {
+	    id: string;
+	    name: string;
+	    size: number;
+	    added: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Attachment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.added = source["added"];
+	    }
+	}
+	export class Card {
+	    cardholder: string;
+	    number: string;
+	    expiryMonth: number;
+	    expiryYear: number;
+	    cvv: string;
+	    pin: string;
+	    brand: string;
+	    billingAddress: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Card(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.cardholder = source["cardholder"];
+	        this.number = source["number"];
+	        this.expiryMonth = source["expiryMonth"];
+	        this.expiryYear = source["expiryYear"];
+	        this.cvv = source["cvv"];
+	        this.pin = source["pin"];
+	        this.brand = source["brand"];
+	        this.billingAddress = source["billingAddress"];
+	    }
+	}
+	export class Field {
+	    name: string;
+	    value: string;
+	    kind: string;
+	    order: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Field(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	        this.kind = source["kind"];
+	        this.order = source["order"];
+	    }
+	}
 	export class Group {
 	    created: number;
 	    updated: number;
@@ -52,6 +114,122 @@ export namespace database {
 		    return a;
 		}
 	}
+	export class Identity {
+	    documentType: string;
+	    number: string;
+	    issuingAuthority: string;
+	    issuingCountry: string;
+	    issueDate: string;
+	    expiryDate: string;
+	    holderName: string;
+	    dateOfBirth: string;
+	    photoId: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Identity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.documentType = source["documentType"];
+	        this.number = source["number"];
+	        this.issuingAuthority = source["issuingAuthority"];
+	        this.issuingCountry = source["issuingCountry"];
+	        this.issueDate = source["issueDate"];
+	        this.expiryDate = source["expiryDate"];
+	        this.holderName = source["holderName"];
+	        this.dateOfBirth = source["dateOfBirth"];
+	        this.photoId = source["photoId"];
+	    }
+	}
+	export class SSHKey {
+	    privateKey: string;
+	    publicKey: string;
+	    comment: string;
+	    passphrase: string;
+	    fingerprint: string;
+	    agent: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.privateKey = source["privateKey"];
+	        this.publicKey = source["publicKey"];
+	        this.comment = source["comment"];
+	        this.passphrase = source["passphrase"];
+	        this.fingerprint = source["fingerprint"];
+	        this.agent = source["agent"];
+	    }
+	}
+	export class Note {
+	    body: string;
+	    format: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Note(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.body = source["body"];
+	        this.format = source["format"];
+	    }
+	}
+	export class OAuth {
+	    provider: string;
+	    itemId: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OAuth(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.itemId = source["itemId"];
+	    }
+	}
+	export class QA {
+	    id: string;
+	    question: string;
+	    answer: string;
+	    hasAnswer: boolean;
+	    ruleset: Ruleset;
+	
+	    static createFrom(source: any = {}) {
+	        return new QA(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.question = source["question"];
+	        this.answer = source["answer"];
+	        this.hasAnswer = source["hasAnswer"];
+	        this.ruleset = this.convertValues(source["ruleset"], Ruleset);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class IterationConstraint {
 	    type: string;
 	    iterations: number;
@@ -119,9 +297,12 @@ export namespace database {
 	export class Item {
 	    created: number;
 	    updated: number;
+	    lastUsed: number;
 	    type: string;
 	    title: string;
 	    archived: boolean;
+	    customFields: Field[];
+	    attachments: Attachment[];
 	    email: string;
 	    username: string;
 	    password: string;
@@ -131,6 +312,13 @@ export namespace database {
 	    twoFactorSecret: string;
 	    notes: string;
 	    ruleset: Ruleset;
+	    securityQuestions: QA[];
+	    oauth: OAuth;
+	    autoType: string;
+	    identity: Identity;
+	    card: Card;
+	    note: Note;
+	    sshKey: SSHKey;
 	
 	    static createFrom(source: any = {}) {
 	        return new Item(source);
@@ -140,9 +328,12 @@ export namespace database {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.created = source["created"];
 	        this.updated = source["updated"];
+	        this.lastUsed = source["lastUsed"];
 	        this.type = source["type"];
 	        this.title = source["title"];
 	        this.archived = source["archived"];
+	        this.customFields = this.convertValues(source["customFields"], Field);
+	        this.attachments = this.convertValues(source["attachments"], Attachment);
 	        this.email = source["email"];
 	        this.username = source["username"];
 	        this.password = source["password"];
@@ -152,6 +343,13 @@ export namespace database {
 	        this.twoFactorSecret = source["twoFactorSecret"];
 	        this.notes = source["notes"];
 	        this.ruleset = this.convertValues(source["ruleset"], Ruleset);
+	        this.securityQuestions = this.convertValues(source["securityQuestions"], QA);
+	        this.oauth = this.convertValues(source["oauth"], OAuth);
+	        this.autoType = source["autoType"];
+	        this.identity = this.convertValues(source["identity"], Identity);
+	        this.card = this.convertValues(source["card"], Card);
+	        this.note = this.convertValues(source["note"], Note);
+	        this.sshKey = this.convertValues(source["sshKey"], SSHKey);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -211,6 +409,10 @@ export namespace database {
 	    groupIds: string[];
 	    mask: string[];
 	    rulesetMask: string[];
+	    identityMask: string[];
+	    cardMask: string[];
+	    noteMask: string[];
+	    sshKeyMask: string[];
 	    includeGroupIds: boolean;
 	
 	    static createFrom(source: any = {}) {
@@ -224,6 +426,10 @@ export namespace database {
 	        this.groupIds = source["groupIds"];
 	        this.mask = source["mask"];
 	        this.rulesetMask = source["rulesetMask"];
+	        this.identityMask = source["identityMask"];
+	        this.cardMask = source["cardMask"];
+	        this.noteMask = source["noteMask"];
+	        this.sshKeyMask = source["sshKeyMask"];
 	        this.includeGroupIds = source["includeGroupIds"];
 	    }
 	
@@ -246,6 +452,67 @@ export namespace database {
 		}
 	}
 	
+	
+	
+	
+	
+	
+	export class Settings {
+	    sessionLength: number;
+	    trashRetention: number;
+	    expiryWarning: number;
+	    minimizedLock: number;
+	    clipboardClear: number;
+	    breachCorpus: string;
+	    breachOnline: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionLength = source["sessionLength"];
+	        this.trashRetention = source["trashRetention"];
+	        this.expiryWarning = source["expiryWarning"];
+	        this.minimizedLock = source["minimizedLock"];
+	        this.clipboardClear = source["clipboardClear"];
+	        this.breachCorpus = source["breachCorpus"];
+	        this.breachOnline = source["breachOnline"];
+	    }
+	}
+	export class SettingsUpdate {
+	    settings: Settings;
+	    mask: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SettingsUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.settings = this.convertValues(source["settings"], Settings);
+	        this.mask = source["mask"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 